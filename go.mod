@@ -5,12 +5,13 @@ go 1.23.5
 require (
 	github.com/alecthomas/kong v1.8.1
 	github.com/fatih/color v1.18.0
+	github.com/jstemmer/go-junit-report/v2 v2.1.0
+	github.com/klauspost/compress v1.18.0
 	github.com/sirupsen/logrus v1.9.3
 	golang.org/x/term v0.31.0
 )
 
 require (
-	github.com/jstemmer/go-junit-report/v2 v2.1.0 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
 	github.com/stretchr/testify v1.8.0 // indirect
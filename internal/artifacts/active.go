@@ -0,0 +1,87 @@
+package artifacts
+
+import (
+	"io"
+	"sync"
+
+	stormartifacts "github.com/microsoft/storm/pkg/storm/artifacts"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	activeSuiteBroker      *ArtifactBroker
+	activeSuiteBrokerMutex sync.Mutex
+)
+
+// SetActiveSuiteBroker sets the broker artifacts published through the suite
+// are forwarded to while a scenario or helper runs. It returns a function
+// restoring the previous broker.
+func SetActiveSuiteBroker(broker *ArtifactBroker) (restore func()) {
+	activeSuiteBrokerMutex.Lock()
+	defer activeSuiteBrokerMutex.Unlock()
+
+	previous := activeSuiteBroker
+	activeSuiteBroker = broker
+	return func() {
+		activeSuiteBrokerMutex.Lock()
+		defer activeSuiteBrokerMutex.Unlock()
+		activeSuiteBroker = previous
+	}
+}
+
+// suiteBroker is the artifact broker of the suite. Artifacts are published
+// to the run of the scenario or helper that is running, if any.
+type suiteBroker struct {
+	logger *logrus.Logger
+}
+
+// NewSuiteBroker creates the artifact broker of a suite. Artifacts published
+// when no scenario or helper is running are dropped with a warning written to
+// the given logger.
+func NewSuiteBroker(logger *logrus.Logger) stormartifacts.ArtifactBroker {
+	return &suiteBroker{logger: logger}
+}
+
+// active returns the broker of the running scenario or helper, or nil after
+// warning that the artifact with the given name is dropped.
+func (b *suiteBroker) active(name string) *ArtifactBroker {
+	activeSuiteBrokerMutex.Lock()
+	defer activeSuiteBrokerMutex.Unlock()
+
+	if activeSuiteBroker == nil {
+		b.logger.Warnf("Not publishing suite artifact '%s' because no scenario or helper is running", name)
+	}
+
+	return activeSuiteBroker
+}
+
+func (b *suiteBroker) PublishLogFile(name string, source string) {
+	if broker := b.active(name); broker != nil {
+		broker.PublishLogFile(name, source)
+	}
+}
+
+func (b *suiteBroker) PublishFile(name string, source string, metadata stormartifacts.Metadata) {
+	if broker := b.active(name); broker != nil {
+		broker.PublishFile(name, source, metadata)
+	}
+}
+
+func (b *suiteBroker) PublishDirectory(name string, source string, metadata stormartifacts.Metadata) {
+	if broker := b.active(name); broker != nil {
+		broker.PublishDirectory(name, source, metadata)
+	}
+}
+
+func (b *suiteBroker) PublishBytes(name string, data []byte, metadata stormartifacts.Metadata) {
+	if broker := b.active(name); broker != nil {
+		broker.PublishBytes(name, data, metadata)
+	}
+}
+
+func (b *suiteBroker) PublishReader(name string, reader io.Reader, metadata stormartifacts.Metadata) {
+	if broker := b.active(name); broker != nil {
+		broker.PublishReader(name, reader, metadata)
+	}
+}
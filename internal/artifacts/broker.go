@@ -1,37 +1,204 @@
 package artifacts
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 
-	"github.com/microsoft/storm/pkg/storm/core"
+	stormartifacts "github.com/microsoft/storm/pkg/storm/artifacts"
+	"github.com/microsoft/storm/pkg/storm/results"
 )
 
 type ArtifactBroker struct {
 	// The parent artifact manager.
 	manager *ArtifactManager
 
-	// The test case this broker is attached to.
-	testCase core.TestCase
+	// The test case, or other owner, this broker is attached to.
+	owner Owner
+
+	// The artifacts published so far through this broker. Test cases may
+	// publish from several goroutines.
+	published      []results.Artifact
+	publishedMutex sync.Mutex
+}
+
+// Attach attaches the broker to the test case, or other owner, its artifacts
+// are published for.
+func (b *ArtifactBroker) Attach(owner Owner) {
+	b.owner = owner
 }
 
-func (b *ArtifactBroker) AttachTestCase(tc core.TestCase) {
-	b.testCase = tc
+// Published returns the artifacts published through this broker, in the order
+// they were published.
+func (b *ArtifactBroker) Published() []results.Artifact {
+	b.publishedMutex.Lock()
+	defer b.publishedMutex.Unlock()
+	return append([]results.Artifact(nil), b.published...)
 }
 
 func (b *ArtifactBroker) PublishLogFile(name string, source string) {
-	if b.testCase == nil {
+	b.PublishFile(name, source, stormartifacts.Metadata{
+		ContentType: "text/plain",
+	})
+}
+
+func (b *ArtifactBroker) PublishFile(name string, source string, metadata stormartifacts.Metadata) {
+	b.publishFile(nil, name, source, metadata)
+}
+
+func (b *ArtifactBroker) PublishDirectory(name string, source string, metadata stormartifacts.Metadata) {
+	b.publishDirectory(nil, name, source, metadata)
+}
+
+func (b *ArtifactBroker) PublishBytes(name string, data []byte, metadata stormartifacts.Metadata) {
+	b.publishReader(nil, name, bytes.NewReader(data), metadata)
+}
+
+func (b *ArtifactBroker) PublishReader(name string, reader io.Reader, metadata stormartifacts.Metadata) {
+	b.publishReader(nil, name, reader, metadata)
+}
+
+// Sub returns a broker publishing artifacts through b to the given directory
+// of its owner's directory. They are reported along with the owner's own
+// artifacts. Failures to publish through it are only logged, so that it can
+// be used once a test case has finished without changing its status.
+func (b *ArtifactBroker) Sub(dir string) stormartifacts.ArtifactBroker {
+	return &subBroker{parent: b, dir: dir}
+}
+
+func (b *ArtifactBroker) publishFile(report func(error), name string, source string, metadata stormartifacts.Metadata) {
+	b.publish(report, fmt.Sprintf("file %s from path %s", name, source), func() (*results.Artifact, error) {
+		return b.manager.publishFile(b.owner, name, source, metadata)
+	})
+}
+
+func (b *ArtifactBroker) publishDirectory(report func(error), name string, source string, metadata stormartifacts.Metadata) {
+	b.publish(report, fmt.Sprintf("directory %s from path %s", name, source), func() (*results.Artifact, error) {
+		return b.manager.publishDirectory(b.owner, name, source, metadata)
+	})
+}
+
+func (b *ArtifactBroker) publishReader(report func(error), name string, reader io.Reader, metadata stormartifacts.Metadata) {
+	b.publish(report, fmt.Sprintf("data %s", name), func() (*results.Artifact, error) {
+		return b.manager.publishReader(b.owner, name, reader, metadata)
+	})
+}
+
+// publish runs the given publishing function, records the published artifact
+// and updates the owner's manifest. Any error is given to report, or when it
+// is nil, reported by marking the test case as an error, or logged for other
+// owners.
+func (b *ArtifactBroker) publish(report func(error), what string, f func() (*results.Artifact, error)) {
+	if b.owner == nil {
 		// This should never happen as the broker is initialized and attached to
 		// a test case internally by storm, but just in case, we report an
 		// internal error via panic.
-		panic("internal error: Artifact broker was not attached to a test case before publishing a log file")
+		panic("internal error: Artifact broker was not attached to a test case or step before publishing an artifact")
 	}
 
 	if b.manager == nil {
-		panic("internal error: Artifact broker was not attached to an artifact manager before publishing a log file")
+		panic("internal error: Artifact broker was not attached to an artifact manager before publishing an artifact")
+	}
+
+	artifact, err := f()
+	if err == nil && artifact != nil {
+		err = b.record(*artifact)
+	}
+
+	// Size limits are not an error of the test case, they are reported in its
+	// output and in the artifact's description.
+	if err == nil && artifact != nil && (artifact.Truncated || artifact.Rejected) {
+		b.owner.Logger().Warnf("Artifact '%s' was %s", artifact.Name, artifact.Reason)
+	}
+
+	if err == nil {
+		return
+	}
+
+	err = fmt.Errorf("failed to publish %s: %w", what, err)
+	if report != nil {
+		report(err)
+	} else {
+		b.owner.Error(err)
+	}
+}
+
+// record adds an artifact to the list of published artifacts, replacing any
+// artifact previously published with the same name, and writes the manifest.
+func (b *ArtifactBroker) record(artifact results.Artifact) error {
+	b.publishedMutex.Lock()
+	defer b.publishedMutex.Unlock()
+
+	b.published = slices.DeleteFunc(b.published, func(a results.Artifact) bool {
+		return a.Name == artifact.Name
+	})
+	b.published = append(b.published, artifact)
+	return b.manager.writeManifest(b.owner, b.published)
+}
+
+// Finish is called once the test case has finished. When bundling is enabled,
+// it bundles the artifacts of the test case into a tarball. The artifacts are
+// then announced to Azure DevOps and uploaded to the artifact storage in the
+// background, if configured. Errors are logged, since the test case can no
+// longer be affected.
+func (b *ArtifactBroker) Finish() {
+	b.publishedMutex.Lock()
+	if len(b.published) == 0 {
+		b.publishedMutex.Unlock()
+		return
+	}
+
+	if b.manager.opts.Bundle {
+		b.bundle()
+	}
+
+	published := append([]results.Artifact(nil), b.published...)
+	b.publishedMutex.Unlock()
+
+	b.manager.announce(b.owner, published)
+	b.manager.upload(b.owner, published, b.recordUpload)
+}
+
+// recordUpload records the outcome of uploading the artifact with the given
+// name to the artifact storage.
+func (b *ArtifactBroker) recordUpload(name string, url string, err error) {
+	b.publishedMutex.Lock()
+	defer b.publishedMutex.Unlock()
+
+	for i := range b.published {
+		artifact := &b.published[i]
+		if artifact.Name != name {
+			continue
+		}
+
+		if err != nil {
+			artifact.UploadError = err.Error()
+		} else {
+			artifact.URL = url
+		}
 	}
+}
 
-	err := b.manager.publishLogFile(b.testCase, name, source)
+// bundle bundles the published artifacts into a tarball and updates their
+// locations.
+func (b *ArtifactBroker) bundle() {
+	bundle, err := b.manager.bundle(b.owner, b.published)
 	if err != nil {
-		b.testCase.Error(fmt.Errorf("failed to publish log file %s from path %s: %w", name, source, err))
+		b.manager.suite.Logger().Errorf("Failed to bundle the artifacts of '%s': %v", b.owner.Name(), err)
+		return
+	}
+
+	for i := range b.published {
+		artifact := &b.published[i]
+		if artifact.Rejected {
+			continue
+		}
+
+		artifact.Bundle = filepath.ToSlash(bundle)
+		artifact.Path = strings.TrimPrefix(artifact.Path, b.owner.Name()+"/")
 	}
 }
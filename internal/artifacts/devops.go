@@ -0,0 +1,95 @@
+package artifacts
+
+import (
+	"io/fs"
+	"path"
+	"path/filepath"
+
+	"github.com/microsoft/storm/internal/devops"
+	"github.com/microsoft/storm/pkg/storm/results"
+)
+
+// devopsArtifactName returns the name of the pipeline artifact the artifacts
+// of the run are uploaded to.
+func (m *ArtifactManager) devopsArtifactName() string {
+	if m.opts.DevopsArtifactName != "" {
+		return m.opts.DevopsArtifactName
+	}
+
+	return m.suite.Name()
+}
+
+// announce announces the artifacts published by the given test case to Azure
+// DevOps, as configured in the options. It must be called once the test case
+// has finished and its artifacts were bundled, if needed.
+func (m *ArtifactManager) announce(owner Owner, published []results.Artifact) {
+	if !m.suite.AzureDevops() || m.logDir == nil || len(published) == 0 {
+		return
+	}
+
+	switch m.opts.DevopsUpload {
+	case DevopsUploadArtifact:
+		// Bundled artifacts all live in the same tarball, otherwise the whole
+		// directory of the test case is uploaded, manifest included.
+		folder := path.Join(owner.Registrant().Name(), owner.Name())
+		location := owner.Name()
+		for _, artifact := range published {
+			if artifact.Bundle != "" {
+				folder = owner.Registrant().Name()
+				location = artifact.Bundle
+				break
+			}
+		}
+
+		devops.UploadArtifact(m.devopsArtifactName(), folder, m.absolute(location))
+
+	case DevopsUploadAttachment:
+		uploaded := make(map[string]bool)
+		for _, artifact := range published {
+			location := artifact.Location()
+			if location == "" || uploaded[location] {
+				continue
+			}
+			uploaded[location] = true
+
+			// Only files can be attached, so directories are attached one
+			// file at a time.
+			if artifact.Kind == results.ArtifactKindDirectory && artifact.Bundle == "" {
+				m.announceDirectory(m.absolute(location))
+			} else {
+				devops.UploadFile(m.absolute(location))
+			}
+		}
+	}
+}
+
+// announceDirectory attaches every file in the given directory to the logs of
+// the pipeline run.
+func (m *ArtifactManager) announceDirectory(dir string) {
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.Type().IsRegular() {
+			devops.UploadFile(path)
+		}
+
+		return nil
+	})
+	if err != nil {
+		m.suite.Logger().Warnf("Failed to attach the files of %s: %v", dir, err)
+	}
+}
+
+// absolute returns the absolute path of a location relative to the log
+// directory, as expected by the Azure DevOps agent.
+func (m *ArtifactManager) absolute(location string) string {
+	path := filepath.Join(*m.logDir, filepath.FromSlash(location))
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+
+	return abs
+}
@@ -1,10 +1,17 @@
 package artifacts
 
 import (
+	"archive/tar"
+	"compress/gzip"
 	"fmt"
 	"io"
+	"io/fs"
+	"mime"
+	"net/http"
 	"os"
 	"path/filepath"
+
+	"github.com/klauspost/compress/zstd"
 )
 
 // CopyFile copies a file from srcPath to destPath.
@@ -44,3 +51,300 @@ func MkdirParents(path string, perm os.FileMode) error {
 
 	return nil
 }
+
+// storedFile describes a file written by storeFile.
+type storedFile struct {
+	// Path the file was written to, including any compression suffix.
+	path string
+
+	// Number of bytes of content read.
+	read int64
+
+	// Number of bytes written to disk.
+	size int64
+
+	// Whether the content was cut short by the limit.
+	truncated bool
+
+	// First bytes of the content, to detect its type.
+	head []byte
+}
+
+// storeFile writes at most limit bytes read from reader, or everything if
+// limit is negative, to a file at destPath, creating its parent directories as
+// needed. With compression, the file is compressed and the suffix of the
+// compression is added to its path.
+func storeFile(destPath string, reader io.Reader, limit int64, compression Compression) (storedFile, error) {
+	stored := storedFile{path: destPath + compression.Suffix()}
+
+	err := MkdirParents(stored.path, 0o755)
+	if err != nil {
+		return stored, err
+	}
+
+	output, err := os.Create(stored.path)
+	if err != nil {
+		return stored, fmt.Errorf("failed to create destination file %s: %w", stored.path, err)
+	}
+	defer output.Close()
+
+	counter := &countingWriter{w: output}
+	var content io.Writer = counter
+	compressor, err := newCompressor(counter, compression)
+	if err != nil {
+		return stored, err
+	}
+
+	if compressor != nil {
+		content = compressor
+	}
+
+	head := &headWriter{w: content, max: 512}
+	if limit < 0 {
+		stored.read, err = io.Copy(head, reader)
+	} else {
+		stored.read, err = io.CopyN(head, reader, limit)
+		if err == io.EOF {
+			err = nil
+		} else if err == nil {
+			// Anything left to read is cut off.
+			var extra [1]byte
+			n, _ := io.ReadFull(reader, extra[:])
+			stored.truncated = n > 0
+		}
+	}
+
+	if err != nil {
+		return stored, fmt.Errorf("failed to write data to %s: %w", stored.path, err)
+	}
+
+	if compressor != nil {
+		err = compressor.Close()
+		if err != nil {
+			return stored, fmt.Errorf("failed to compress data to %s: %w", stored.path, err)
+		}
+	}
+
+	stored.size = counter.n
+	stored.head = head.data
+	return stored, nil
+}
+
+// CopyDirectory copies the directory at srcPath and everything in it to
+// destPath, compressing every file as requested. Symbolic links are recreated
+// rather than followed, and anything other than regular files, directories and
+// links is skipped. It returns the total size of the files read and written.
+func CopyDirectory(srcPath, destPath string, compression Compression) (read int64, written int64, err error) {
+	err = filepath.WalkDir(srcPath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(srcPath, path)
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(destPath, rel)
+		switch {
+		case entry.IsDir():
+			return os.MkdirAll(target, 0o755)
+		case entry.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return fmt.Errorf("failed to read link %s: %w", path, err)
+			}
+
+			os.Remove(target)
+			return os.Symlink(link, target)
+		case entry.Type().IsRegular():
+			input, err := os.Open(path)
+			if err != nil {
+				return fmt.Errorf("failed to open source file %s: %w", path, err)
+			}
+			defer input.Close()
+
+			stored, err := storeFile(target, input, -1, compression)
+			read += stored.read
+			written += stored.size
+			return err
+		default:
+			return nil
+		}
+	})
+
+	if err != nil {
+		return read, written, fmt.Errorf("failed to copy directory %s to %s: %w", srcPath, destPath, err)
+	}
+
+	return read, written, nil
+}
+
+// DirectorySize returns the total size of the regular files in a directory.
+func DirectorySize(path string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(path, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.Type().IsRegular() {
+			return err
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		total += info.Size()
+		return nil
+	})
+
+	return total, err
+}
+
+// BundleDirectory writes the directory at srcPath and everything in it to a
+// tarball at destPath, compressed as requested, and returns the path of the
+// tarball, with the suffix of the compression added.
+func BundleDirectory(srcPath, destPath string, compression Compression) (string, error) {
+	destPath += compression.Suffix()
+
+	output, err := os.Create(destPath)
+	if err != nil {
+		return destPath, fmt.Errorf("failed to create bundle %s: %w", destPath, err)
+	}
+	defer output.Close()
+
+	var w io.Writer = output
+	compressor, err := newCompressor(output, compression)
+	if err != nil {
+		return destPath, err
+	}
+
+	if compressor != nil {
+		w = compressor
+	}
+
+	tw := tar.NewWriter(w)
+	err = filepath.WalkDir(srcPath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || path == srcPath {
+			return err
+		}
+
+		return addToTar(tw, srcPath, path, entry)
+	})
+
+	if err == nil {
+		err = tw.Close()
+	}
+
+	if err == nil && compressor != nil {
+		err = compressor.Close()
+	}
+
+	if err != nil {
+		return destPath, fmt.Errorf("failed to bundle %s into %s: %w", srcPath, destPath, err)
+	}
+
+	return destPath, nil
+}
+
+// newCompressor returns a writer compressing what is written to it into w, or
+// nil without compression. It must be closed to write out the compressed data.
+func newCompressor(w io.Writer, compression Compression) (io.WriteCloser, error) {
+	switch compression {
+	case CompressionGzip:
+		return gzip.NewWriter(w), nil
+	case CompressionZstd:
+		encoder, err := zstd.NewWriter(w)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create zstd encoder: %w", err)
+		}
+
+		return encoder, nil
+	default:
+		return nil, nil
+	}
+}
+
+// addToTar adds the given entry of the directory at root to a tarball.
+// Anything other than regular files, directories and links is skipped.
+func addToTar(tw *tar.Writer, root string, path string, entry fs.DirEntry) error {
+	info, err := entry.Info()
+	if err != nil {
+		return err
+	}
+
+	link := ""
+	if info.Mode()&fs.ModeSymlink != 0 {
+		link, err = os.Readlink(path)
+		if err != nil {
+			return err
+		}
+	} else if !info.Mode().IsRegular() && !info.IsDir() {
+		return nil
+	}
+
+	header, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return err
+	}
+
+	header.Name = filepath.ToSlash(rel)
+	if info.IsDir() {
+		header.Name += "/"
+	}
+
+	err = tw.WriteHeader(header)
+	if err != nil || !info.Mode().IsRegular() {
+		return err
+	}
+
+	input, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	_, err = io.Copy(tw, input)
+	return err
+}
+
+// DetectContentType guesses the MIME type of content from the extension of
+// its name, or from its first bytes if the extension is not known.
+func DetectContentType(name string, head []byte) string {
+	if contentType := mime.TypeByExtension(filepath.Ext(name)); contentType != "" {
+		return contentType
+	}
+
+	return http.DetectContentType(head)
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// headWriter keeps a copy of the first bytes written through it.
+type headWriter struct {
+	w    io.Writer
+	max  int
+	data []byte
+}
+
+func (h *headWriter) Write(p []byte) (int, error) {
+	if missing := h.max - len(h.data); missing > 0 {
+		h.data = append(h.data, p[:min(missing, len(p))]...)
+	}
+
+	return h.w.Write(p)
+}
@@ -0,0 +1,147 @@
+package artifacts
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	stormartifacts "github.com/microsoft/storm/pkg/storm/artifacts"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestStoreFile(t *testing.T) {
+	t.Run("truncated", func(t *testing.T) {
+		dest := filepath.Join(t.TempDir(), "out.log")
+		stored, err := storeFile(dest, strings.NewReader("hello world"), 5, CompressionNone)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if !stored.truncated || stored.read != 5 || stored.size != 5 {
+			t.Errorf("expected 5 bytes stored and truncation, got %+v", stored)
+		}
+
+		data, _ := os.ReadFile(dest)
+		if string(data) != "hello" {
+			t.Errorf("expected 'hello', got '%s'", data)
+		}
+	})
+
+	t.Run("exact limit", func(t *testing.T) {
+		dest := filepath.Join(t.TempDir(), "out.log")
+		stored, err := storeFile(dest, strings.NewReader("hello"), 5, CompressionNone)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if stored.truncated {
+			t.Errorf("expected no truncation when the data fits exactly")
+		}
+	})
+
+	t.Run("gzip", func(t *testing.T) {
+		dest := filepath.Join(t.TempDir(), "out.log")
+		stored, err := storeFile(dest, strings.NewReader("hello world"), -1, CompressionGzip)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if stored.path != dest+".gz" || stored.read != 11 {
+			t.Errorf("expected 11 bytes read into %s.gz, got %+v", dest, stored)
+		}
+
+		file, err := os.Open(stored.path)
+		if err != nil {
+			t.Fatalf("failed to open compressed file: %v", err)
+		}
+		defer file.Close()
+
+		reader, err := gzip.NewReader(file)
+		if err != nil {
+			t.Fatalf("failed to read compressed file: %v", err)
+		}
+
+		data, _ := io.ReadAll(reader)
+		if string(data) != "hello world" {
+			t.Errorf("expected 'hello world', got '%s'", data)
+		}
+	})
+
+	t.Run("zstd", func(t *testing.T) {
+		dest := filepath.Join(t.TempDir(), "out.log")
+		stored, err := storeFile(dest, strings.NewReader("hello world"), -1, CompressionZstd)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if stored.path != dest+".zst" || stored.read != 11 {
+			t.Errorf("expected 11 bytes read into %s.zst, got %+v", dest, stored)
+		}
+
+		file, err := os.Open(stored.path)
+		if err != nil {
+			t.Fatalf("failed to open compressed file: %v", err)
+		}
+		defer file.Close()
+
+		reader, err := zstd.NewReader(file)
+		if err != nil {
+			t.Fatalf("failed to read compressed file: %v", err)
+		}
+		defer reader.Close()
+
+		data, _ := io.ReadAll(reader)
+		if string(data) != "hello world" {
+			t.Errorf("expected 'hello world', got '%s'", data)
+		}
+	})
+}
+
+func TestBundleChargesBudget(t *testing.T) {
+	logDir := t.TempDir()
+	manager := NewArtifactManager(nil, &logDir, Options{MaxTotalSize: 1 << 20, Compression: CompressionZstd, Bundle: true})
+	broker := manager.NewBroker()
+	broker.Attach(&recordingOwner{})
+
+	broker.PublishBytes("data.txt", []byte(strings.Repeat("storm ", 10000)), stormartifacts.Metadata{})
+	broker.bundle()
+
+	tarball := filepath.Join(logDir, "test.tar.zst")
+	info, err := os.Stat(tarball)
+	if err != nil {
+		t.Fatalf("expected a zstd bundle, got %v", err)
+	}
+
+	if remaining := manager.budget.remaining; remaining != 1<<20-info.Size() {
+		t.Errorf("expected the bundle's size of %d bytes to be charged in place of its files, %d bytes remain", info.Size(), remaining)
+	}
+
+	file, _ := os.Open(tarball)
+	defer file.Close()
+	reader, err := zstd.NewReader(file)
+	if err != nil {
+		t.Fatalf("failed to read bundle: %v", err)
+	}
+	defer reader.Close()
+
+	var names []string
+	archive := tar.NewReader(reader)
+	for {
+		header, err := archive.Next()
+		if err != nil {
+			break
+		}
+
+		names = append(names, header.Name)
+	}
+
+	if !slices.Contains(names, "data.txt") {
+		t.Errorf("expected the bundle to hold the data, got %v", names)
+	}
+}
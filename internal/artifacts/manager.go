@@ -1,25 +1,49 @@
 package artifacts
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"time"
 
+	stormartifacts "github.com/microsoft/storm/pkg/storm/artifacts"
 	"github.com/microsoft/storm/pkg/storm/core"
+	"github.com/microsoft/storm/pkg/storm/results"
 )
 
+// Name of the manifest listing the artifacts published by a test case, written
+// to the test case's artifact directory.
+const ManifestFileName = "_artifacts.json"
+
 type ArtifactManager struct {
-	suite  core.SuiteContext
-	logDir *string
+	suite    core.SuiteContext
+	logDir   *string
+	opts     Options
+	budget   *sizeBudget
+	uploader *uploader
+
+	// Identifies the run in the keys of uploaded artifacts.
+	runID string
 }
 
-// NewArtifactManager creates a new artifact manager. If logDir is nil, no
-// log artifacts will be saved.
-func NewArtifactManager(suite core.SuiteContext, logDir *string) *ArtifactManager {
-	return &ArtifactManager{
+// NewArtifactManager creates a new artifact manager storing artifacts as
+// described by opts. If logDir is nil, no log artifacts will be saved.
+func NewArtifactManager(suite core.SuiteContext, logDir *string, opts Options) *ArtifactManager {
+	manager := &ArtifactManager{
 		suite:  suite,
 		logDir: logDir,
+		opts:   opts,
+		budget: newSizeBudget(opts),
+		runID:  time.Now().UTC().Format("20060102-150405.000"),
+	}
+
+	if opts.Storage != nil {
+		manager.uploader = newUploader(suite.Context(), opts.Storage)
 	}
+
+	return manager
 }
 
 // NewBroker creates a new artifact child broker that is attached to this
@@ -31,39 +55,244 @@ func (m *ArtifactManager) NewBroker() *ArtifactBroker {
 	}
 }
 
-// publishLogFile is the internal implementation of publishing a log file. It
-// is called by the artifact broker when a test case wants to publish a log
-// file.
-func (b *ArtifactManager) publishLogFile(testcase core.TestCase, name string, source string) error {
-	if b.logDir == nil {
-		b.suite.Logger().Warnf("Not publishing log file '%s' because no log directory was configured", name)
-		return nil
+// compression returns the compression applied to individual files, which is
+// none when artifacts are bundled since the bundle is compressed instead.
+func (m *ArtifactManager) compression() Compression {
+	if m.opts.Bundle {
+		return CompressionNone
+	}
+
+	return m.opts.Compression
+}
+
+// destination returns the path an artifact with the given name published by
+// the given test case is saved to, relative to the log directory. It returns
+// false if no log directory was configured, in which case the artifact must be
+// dropped.
+func (m *ArtifactManager) destination(owner Owner, kind string, name string) (string, bool, error) {
+	if m.logDir == nil {
+		m.suite.Logger().Warnf("Not publishing %s '%s' because no log directory was configured", kind, name)
+		return "", false, nil
+	}
+
+	if !filepath.IsLocal(name) {
+		return "", false, fmt.Errorf("artifact name '%s' must be a relative path within the test case's directory", name)
+	}
+
+	if filepath.Clean(name) == ManifestFileName {
+		return "", false, fmt.Errorf("artifact name '%s' is reserved for the manifest of the test case", name)
+	}
+
+	return filepath.Join(owner.Name(), name), true, nil
+}
+
+// newArtifact describes an artifact with the given name, saved at the given
+// path relative to the log directory.
+func newArtifact(name string, path string, kind results.ArtifactKind, metadata stormartifacts.Metadata) *results.Artifact {
+	return &results.Artifact{
+		Name:        name,
+		Path:        filepath.ToSlash(path),
+		Kind:        kind,
+		ContentType: metadata.ContentType,
+		Description: metadata.Description,
+		Time:        time.Now(),
+	}
+}
+
+// publishFile is the internal implementation of publishing a file. It is
+// called by the artifact broker when a test case wants to publish a file. It
+// returns nil if the file was not published because no log directory was
+// configured.
+func (m *ArtifactManager) publishFile(owner Owner, name string, source string, metadata stormartifacts.Metadata) (*results.Artifact, error) {
+	if m.logDir == nil {
+		_, _, err := m.destination(owner, "file", name)
+		return nil, err
 	}
 
 	source, err := filepath.Abs(source)
 	if err != nil {
-		return fmt.Errorf("failed to get absolute path for %s: %w", source, err)
+		return nil, fmt.Errorf("failed to get absolute path for %s: %w", source, err)
 	}
 
 	info, err := os.Stat(source)
 	if err != nil {
-		return fmt.Errorf("failed to stat file %s: %w", source, err)
+		return nil, fmt.Errorf("failed to stat file %s: %w", source, err)
 	}
 
 	if !info.Mode().IsRegular() {
-		return fmt.Errorf("path %s is not a regular file", source)
+		return nil, fmt.Errorf("path %s is not a regular file", source)
 	}
 
-	destPath := filepath.Join(*b.logDir, testcase.Name(), name)
-	err = MkdirParents(destPath, 0o755)
+	input, err := os.Open(source)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to open source file %s: %w", source, err)
+	}
+	defer input.Close()
+
+	return m.publishReader(owner, name, input, metadata)
+}
+
+// publishReader is the internal implementation of publishing data read from a
+// reader. The data is truncated if it does not fit in the size limits. It
+// returns nil if the data was not published because no log directory was
+// configured.
+func (m *ArtifactManager) publishReader(owner Owner, name string, reader io.Reader, metadata stormartifacts.Metadata) (*results.Artifact, error) {
+	dest, ok, err := m.destination(owner, "data", name)
+	if !ok {
+		return nil, err
+	}
+
+	artifact := newArtifact(name, dest, results.ArtifactKindFile, metadata)
+
+	limit, limitReason := m.budget.reserve()
+	if limit == 0 {
+		artifact.Path = ""
+		artifact.Rejected = true
+		artifact.Reason = fmt.Sprintf("rejected because of %s", limitReason)
+		return artifact, nil
+	}
+
+	stored, err := storeFile(filepath.Join(*m.logDir, dest), reader, limit, m.compression())
+	m.budget.release(limit - stored.read)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := filepath.Rel(*m.logDir, stored.path)
+	if err != nil {
+		return nil, err
+	}
+
+	artifact.Path = filepath.ToSlash(path)
+	artifact.Size = stored.size
+	if m.compression() != CompressionNone {
+		artifact.Compression = string(m.compression())
+	}
+
+	if artifact.ContentType == "" {
+		artifact.ContentType = DetectContentType(name, stored.head)
+	}
+
+	if stored.truncated {
+		artifact.Truncated = true
+		artifact.Reason = fmt.Sprintf("truncated to %s because of %s", FormatSize(stored.read), limitReason)
+	}
+
+	return artifact, nil
+}
+
+// publishDirectory is the internal implementation of publishing a directory.
+// The directory is rejected if it does not fit in the size limits. It returns
+// nil if the directory was not published because no log directory was
+// configured.
+func (m *ArtifactManager) publishDirectory(owner Owner, name string, source string, metadata stormartifacts.Metadata) (*results.Artifact, error) {
+	dest, ok, err := m.destination(owner, "directory", name)
+	if !ok {
+		return nil, err
 	}
 
-	_, err = CopyFile(source, destPath)
+	source, err = filepath.Abs(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path for %s: %w", source, err)
+	}
+
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat directory %s: %w", source, err)
+	}
+
+	if !info.IsDir() {
+		return nil, fmt.Errorf("path %s is not a directory", source)
+	}
+
+	size, err := DirectorySize(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to measure directory %s: %w", source, err)
+	}
+
+	artifact := newArtifact(name, dest, results.ArtifactKindDirectory, metadata)
+
+	limit, limitReason := m.budget.reserve()
+	if limit >= 0 && size > limit {
+		m.budget.release(limit)
+		artifact.Path = ""
+		artifact.Rejected = true
+		artifact.Reason = fmt.Sprintf("rejected because its size of %s exceeds %s", FormatSize(size), limitReason)
+		return artifact, nil
+	}
+
+	read, written, err := CopyDirectory(source, filepath.Join(*m.logDir, dest), m.compression())
+	m.budget.release(limit - read)
+	if err != nil {
+		return nil, err
+	}
+
+	artifact.Size = written
+	if m.compression() != CompressionNone {
+		artifact.Compression = string(m.compression())
+	}
+
+	return artifact, nil
+}
+
+// writeManifest writes the manifest of the artifacts published by the given
+// test case to its artifact directory.
+func (m *ArtifactManager) writeManifest(owner Owner, artifacts []results.Artifact) error {
+	manifest := struct {
+		TestCase  string             `json:"testCase"`
+		Artifacts []results.Artifact `json:"artifacts"`
+	}{
+		TestCase:  owner.Name(),
+		Artifacts: artifacts,
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode artifact manifest: %w", err)
+	}
+
+	path := filepath.Join(*m.logDir, owner.Name(), ManifestFileName)
+	err = MkdirParents(path, 0o755)
 	if err != nil {
 		return err
 	}
 
+	err = os.WriteFile(path, append(data, '\n'), 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to write artifact manifest %s: %w", path, err)
+	}
+
 	return nil
 }
+
+// bundle bundles the artifact directory of the given test case into a tarball
+// next to it, and removes the directory. The size of the tarball is charged to
+// the size budget in place of the given artifacts it holds. It returns the
+// path of the tarball relative to the log directory.
+func (m *ArtifactManager) bundle(owner Owner, artifacts []results.Artifact) (string, error) {
+	dir := filepath.Join(*m.logDir, owner.Name())
+	tarball, err := BundleDirectory(dir, dir+".tar", m.opts.Compression)
+	if err != nil {
+		return "", err
+	}
+
+	info, err := os.Stat(tarball)
+	if err != nil {
+		return "", fmt.Errorf("failed to stat bundle %s: %w", tarball, err)
+	}
+
+	var bundled int64
+	for _, artifact := range artifacts {
+		bundled += artifact.Size
+	}
+
+	m.budget.release(bundled)
+	m.budget.charge(info.Size())
+
+	err = os.RemoveAll(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to remove bundled directory %s: %w", dir, err)
+	}
+
+	return filepath.Rel(*m.logDir, tarball)
+}
@@ -0,0 +1,163 @@
+package artifacts
+
+import (
+	"fmt"
+	"sync"
+
+	stormartifacts "github.com/microsoft/storm/pkg/storm/artifacts"
+)
+
+// Compression is the compression applied to published artifacts.
+type Compression string
+
+const (
+	CompressionNone Compression = "none"
+	CompressionGzip Compression = "gzip"
+	CompressionZstd Compression = "zstd"
+)
+
+// Suffix returns the suffix added to the path of files compressed this way.
+func (c Compression) Suffix() string {
+	switch c {
+	case CompressionGzip:
+		return ".gz"
+	case CompressionZstd:
+		return ".zst"
+	default:
+		return ""
+	}
+}
+
+// DevopsUpload is how published artifacts are announced to Azure DevOps.
+type DevopsUpload string
+
+const (
+	// Artifacts are not announced.
+	DevopsUploadNone DevopsUpload = "none"
+
+	// The artifacts of each test case are uploaded to a pipeline artifact,
+	// in a folder named after the scenario or helper and the test case.
+	DevopsUploadArtifact DevopsUpload = "artifact"
+
+	// The files of each test case are attached to the logs of the pipeline
+	// run.
+	DevopsUploadAttachment DevopsUpload = "attachment"
+)
+
+// Options controls how the artifact manager stores artifacts.
+type Options struct {
+	// Maximum size in bytes of a single artifact, 0 for no limit. Larger files
+	// are truncated, larger directories are rejected.
+	MaxArtifactSize int64
+
+	// Maximum size in bytes of all artifacts of a run, 0 for no limit. Once
+	// reached, files are truncated and then rejected, and directories that do
+	// not fit are rejected.
+	MaxTotalSize int64
+
+	// Compression applied to every published file. When artifacts are
+	// bundled, it is applied to the bundle instead.
+	Compression Compression
+
+	// Bundle the artifact directory of each test case into a tarball once the
+	// test case has finished.
+	Bundle bool
+
+	// How the artifacts of each test case are announced to Azure DevOps once
+	// the test case has finished. Only used with the Azure DevOps integration
+	// enabled.
+	DevopsUpload DevopsUpload
+
+	// Name of the pipeline artifact used with DevopsUploadArtifact. Defaults
+	// to the name of the suite.
+	DevopsArtifactName string
+
+	// Storage the artifacts of each test case are uploaded to once the test
+	// case has finished, if any.
+	Storage stormartifacts.Storage
+}
+
+// sizeBudget keeps track of the size still available for artifacts.
+type sizeBudget struct {
+	mutex     sync.Mutex
+	perItem   int64
+	total     int64
+	remaining int64
+	limited   bool
+}
+
+func newSizeBudget(opts Options) *sizeBudget {
+	return &sizeBudget{
+		perItem:   opts.MaxArtifactSize,
+		total:     opts.MaxTotalSize,
+		remaining: opts.MaxTotalSize,
+		limited:   opts.MaxTotalSize > 0,
+	}
+}
+
+// reserve reserves the size a single artifact may use, which is -1 when there
+// is no limit. The unused part of the reservation must be given back with
+// release. When the reservation is limited, a description of the limit that
+// applies is returned as well.
+func (b *sizeBudget) reserve() (int64, string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	grant := int64(-1)
+	reason := ""
+	if b.perItem > 0 {
+		grant = b.perItem
+		reason = fmt.Sprintf("the per-artifact size limit of %s", FormatSize(b.perItem))
+	}
+
+	if b.limited && (grant < 0 || b.remaining < grant) {
+		grant = b.remaining
+		reason = fmt.Sprintf("the total artifact size limit of %s, with %s left", FormatSize(b.total), FormatSize(b.remaining))
+	}
+
+	if b.limited {
+		b.remaining -= grant
+	}
+
+	return grant, reason
+}
+
+// release gives back an unused part of a reservation.
+func (b *sizeBudget) release(size int64) {
+	if !b.limited || size <= 0 {
+		return
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.remaining += size
+}
+
+// charge takes the given size from the budget without a reservation, for
+// content written regardless of the limits. The budget runs out rather than
+// going below zero.
+func (b *sizeBudget) charge(size int64) {
+	if !b.limited || size <= 0 {
+		return
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.remaining = max(b.remaining-size, 0)
+}
+
+// FormatSize formats a size in bytes with binary units, e.g. "1.5 MiB".
+func FormatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,68 @@
+package artifacts
+
+import (
+	"github.com/microsoft/storm/pkg/storm/core"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Directories holding the artifacts published outside of test cases.
+const (
+	SetupDirName   = "_setup"
+	CleanupDirName = "_cleanup"
+	SuiteDirName   = "_suite"
+)
+
+// Directory of a test case holding the diagnostics collected after it failed
+// or errored.
+const DiagnosticsDirName = "diagnostics"
+
+// Owner is what a broker publishes artifacts for, usually a test case. Its
+// artifacts are saved to the directory named after it in the log directory.
+type Owner interface {
+	core.Named
+
+	// Returns the scenario or helper the owner belongs to.
+	Registrant() core.TestRegistrantMetadata
+
+	// Returns the logger warnings about artifacts are written to.
+	Logger() *logrus.Entry
+
+	// Reports a failure to publish an artifact.
+	Error(err error)
+}
+
+// directoryOwner owns the artifacts published outside of test cases, such as
+// during the setup of a scenario.
+type directoryOwner struct {
+	dir        string
+	registrant core.TestRegistrantMetadata
+	logger     *logrus.Entry
+}
+
+// NewDirectoryOwner creates an owner for artifacts saved to the given
+// directory of the log directory, on behalf of the given registrant. Failures
+// to publish are only logged, since there is no test case to mark as an error.
+func NewDirectoryOwner(dir string, registrant core.TestRegistrantMetadata, logger *logrus.Entry) Owner {
+	return &directoryOwner{
+		dir:        dir,
+		registrant: registrant,
+		logger:     logger,
+	}
+}
+
+func (o *directoryOwner) Name() string {
+	return o.dir
+}
+
+func (o *directoryOwner) Registrant() core.TestRegistrantMetadata {
+	return o.registrant
+}
+
+func (o *directoryOwner) Logger() *logrus.Entry {
+	return o.logger
+}
+
+func (o *directoryOwner) Error(err error) {
+	o.logger.Error(err)
+}
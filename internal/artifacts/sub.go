@@ -0,0 +1,47 @@
+package artifacts
+
+import (
+	"bytes"
+	"io"
+	"path"
+
+	stormartifacts "github.com/microsoft/storm/pkg/storm/artifacts"
+)
+
+// subBroker publishes artifacts through its parent broker to a directory of
+// the parent's owner, logging failures instead of reporting them to the owner.
+type subBroker struct {
+	parent *ArtifactBroker
+	dir    string
+}
+
+func (b *subBroker) PublishLogFile(name string, source string) {
+	b.PublishFile(name, source, stormartifacts.Metadata{
+		ContentType: "text/plain",
+	})
+}
+
+func (b *subBroker) PublishFile(name string, source string, metadata stormartifacts.Metadata) {
+	b.parent.publishFile(b.report, b.name(name), source, metadata)
+}
+
+func (b *subBroker) PublishDirectory(name string, source string, metadata stormartifacts.Metadata) {
+	b.parent.publishDirectory(b.report, b.name(name), source, metadata)
+}
+
+func (b *subBroker) PublishBytes(name string, data []byte, metadata stormartifacts.Metadata) {
+	b.parent.publishReader(b.report, b.name(name), bytes.NewReader(data), metadata)
+}
+
+func (b *subBroker) PublishReader(name string, reader io.Reader, metadata stormartifacts.Metadata) {
+	b.parent.publishReader(b.report, b.name(name), reader, metadata)
+}
+
+// name returns the name of the given artifact within the parent's owner.
+func (b *subBroker) name(name string) string {
+	return path.Join(b.dir, name)
+}
+
+func (b *subBroker) report(err error) {
+	b.parent.owner.Logger().Warn(err)
+}
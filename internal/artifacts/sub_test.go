@@ -0,0 +1,84 @@
+package artifacts
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	stormartifacts "github.com/microsoft/storm/pkg/storm/artifacts"
+	"github.com/microsoft/storm/pkg/storm/core"
+
+	"github.com/sirupsen/logrus"
+)
+
+// recordingOwner records the errors reported to it.
+type recordingOwner struct {
+	errors []error
+}
+
+func (o *recordingOwner) Name() string { return "test" }
+
+func (o *recordingOwner) Registrant() core.TestRegistrantMetadata { return nil }
+
+func (o *recordingOwner) Logger() *logrus.Entry {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logrus.NewEntry(logger)
+}
+
+func (o *recordingOwner) Error(err error) {
+	o.errors = append(o.errors, err)
+}
+
+func TestSubBrokerPublishesToOwnerDirectory(t *testing.T) {
+	logDir := t.TempDir()
+	owner := &recordingOwner{}
+	broker := NewArtifactManager(nil, &logDir, Options{}).NewBroker()
+	broker.Attach(owner)
+
+	sub := broker.Sub("diagnostics/uname")
+	sub.PublishBytes("uname.txt", []byte("Linux"), stormartifacts.Metadata{})
+	sub.PublishFile("missing.txt", filepath.Join(logDir, "missing"), stormartifacts.Metadata{})
+	if len(owner.errors) != 0 {
+		t.Fatalf("expected failures to be logged only, got %v", owner.errors)
+	}
+
+	published := broker.Published()
+	if len(published) != 1 || published[0].Name != "diagnostics/uname/uname.txt" {
+		t.Fatalf("expected the artifact to be published by the parent broker, got %+v", published)
+	}
+
+	data, err := os.ReadFile(filepath.Join(logDir, "test", "diagnostics", "uname", "uname.txt"))
+	if err != nil || string(data) != "Linux" {
+		t.Errorf("expected the artifact to be saved to the owner's directory, got %q, %v", data, err)
+	}
+
+	broker.PublishFile("missing.txt", filepath.Join(logDir, "missing"), stormartifacts.Metadata{})
+	if len(owner.errors) != 1 {
+		t.Errorf("expected the parent broker to report its failure to the owner, got %v", owner.errors)
+	}
+}
+
+func TestBrokerRejectsManifestName(t *testing.T) {
+	logDir := t.TempDir()
+	owner := &recordingOwner{}
+	broker := NewArtifactManager(nil, &logDir, Options{}).NewBroker()
+	broker.Attach(owner)
+
+	broker.PublishBytes("uname.txt", []byte("Linux"), stormartifacts.Metadata{})
+	broker.PublishBytes("./"+ManifestFileName, []byte("clobbered"), stormartifacts.Metadata{})
+	if len(owner.errors) != 1 {
+		t.Fatalf("expected publishing the manifest name to be reported to the owner, got %v", owner.errors)
+	}
+
+	if published := broker.Published(); len(published) != 1 {
+		t.Errorf("expected only the first artifact to be published, got %+v", published)
+	}
+
+	data, err := os.ReadFile(filepath.Join(logDir, "test", ManifestFileName))
+	if err != nil || !strings.Contains(string(data), "uname.txt") {
+		t.Errorf("expected the manifest to be left intact, got %q, %v", data, err)
+	}
+}
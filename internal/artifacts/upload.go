@@ -0,0 +1,201 @@
+package artifacts
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+
+	stormartifacts "github.com/microsoft/storm/pkg/storm/artifacts"
+	"github.com/microsoft/storm/pkg/storm/results"
+)
+
+// Number of files uploaded to the artifact storage at the same time.
+const uploadConcurrency = 4
+
+// Number of times an upload is attempted before giving up.
+const uploadAttempts = 3
+
+// Delay before retrying a failed upload, doubled after every attempt.
+const uploadRetryDelay = 2 * time.Second
+
+// uploader uploads files to an artifact storage in the background, retrying
+// failed uploads.
+type uploader struct {
+	storage    stormartifacts.Storage
+	ctx        context.Context
+	retryDelay time.Duration
+	slots      chan struct{}
+	pending    sync.WaitGroup
+}
+
+func newUploader(ctx context.Context, storage stormartifacts.Storage) *uploader {
+	return &uploader{
+		storage:    storage,
+		ctx:        ctx,
+		retryDelay: uploadRetryDelay,
+		slots:      make(chan struct{}, uploadConcurrency),
+	}
+}
+
+// upload uploads the file at the given path under the given key in the
+// background, and calls done with the outcome. Uploads still waiting for a
+// slot when the context is cancelled are given up on.
+func (u *uploader) upload(key string, path string, done func(error)) {
+	u.pending.Add(1)
+	go func() {
+		defer u.pending.Done()
+
+		select {
+		case u.slots <- struct{}{}:
+		case <-u.ctx.Done():
+			done(fmt.Errorf("not uploaded: %w", context.Cause(u.ctx)))
+			return
+		}
+		defer func() { <-u.slots }()
+
+		done(u.uploadWithRetries(key, path))
+	}()
+}
+
+// uploadWithRetries uploads a file, retrying with an increasing delay until
+// it succeeds, all attempts were made, or the context is cancelled.
+func (u *uploader) uploadWithRetries(key string, path string) error {
+	delay := u.retryDelay
+	for attempt := 1; ; attempt++ {
+		err := u.uploadOnce(key, path)
+		if err == nil {
+			return nil
+		}
+
+		if attempt == uploadAttempts {
+			return fmt.Errorf("failed after %d attempts: %w", attempt, err)
+		}
+
+		select {
+		case <-time.After(delay):
+			delay *= 2
+		case <-u.ctx.Done():
+			return err
+		}
+	}
+}
+
+// uploadOnce makes a single upload attempt, turning panics of the storage into
+// errors since it runs in a background goroutine.
+func (u *uploader) uploadOnce(key string, path string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("artifact storage panicked: %v", r)
+		}
+	}()
+
+	return u.storage.Upload(u.ctx, key, path)
+}
+
+// wait waits for all uploads to finish.
+func (u *uploader) wait() {
+	u.pending.Wait()
+}
+
+// upload uploads the stored artifacts of the given test case to the artifact
+// storage in the background, and calls record with the URL or the error of
+// every artifact once it is done. Artifacts are uploaded under the same
+// relative paths as in the log directory, under a prefix unique to the run.
+func (m *ArtifactManager) upload(owner Owner, published []results.Artifact, record func(name string, url string, err error)) {
+	if m.uploader == nil || m.logDir == nil {
+		return
+	}
+
+	prefix := path.Join(m.suite.Name(), owner.Registrant().Name(), m.runID)
+
+	// Bundled artifacts share the same location, which is only uploaded once.
+	names := make(map[string][]string)
+	var locations []string
+	for _, artifact := range published {
+		location := artifact.Location()
+		if location == "" {
+			continue
+		}
+
+		if _, ok := names[location]; !ok {
+			locations = append(locations, location)
+		}
+		names[location] = append(names[location], artifact.Name)
+	}
+
+	for _, location := range locations {
+		key := path.Join(prefix, location)
+		url := m.uploader.storage.URL(key)
+		done := func(err error) {
+			if err != nil {
+				m.suite.Logger().Warnf("Failed to upload the artifacts of '%s' at %s: %v", owner.Name(), location, err)
+			}
+
+			for _, name := range names[location] {
+				record(name, url, err)
+			}
+		}
+
+		source := filepath.Join(*m.logDir, filepath.FromSlash(location))
+		info, err := os.Stat(source)
+		if err != nil {
+			done(err)
+			continue
+		}
+
+		if info.IsDir() {
+			m.uploadDirectory(key, source, done)
+		} else {
+			m.uploader.upload(key, source, done)
+		}
+	}
+}
+
+// uploadDirectory uploads every file in the given directory under the given
+// key, and calls done once all of them were uploaded, with the first error
+// that occurred.
+func (m *ArtifactManager) uploadDirectory(key string, dir string, done func(error)) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err == nil && entry.Type().IsRegular() {
+			files = append(files, path)
+		}
+		return err
+	})
+	if err != nil || len(files) == 0 {
+		done(err)
+		return
+	}
+
+	var mutex sync.Mutex
+	remaining := len(files)
+	var firstErr error
+	for _, file := range files {
+		rel, _ := filepath.Rel(dir, file)
+		m.uploader.upload(path.Join(key, filepath.ToSlash(rel)), file, func(err error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			if err != nil && firstErr == nil {
+				firstErr = fmt.Errorf("failed to upload %s: %w", rel, err)
+			}
+
+			remaining--
+			if remaining == 0 {
+				done(firstErr)
+			}
+		})
+	}
+}
+
+// WaitUploads waits for all artifacts to be uploaded to the artifact storage.
+func (m *ArtifactManager) WaitUploads() {
+	if m.uploader != nil {
+		m.uploader.wait()
+	}
+}
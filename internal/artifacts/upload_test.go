@@ -0,0 +1,103 @@
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+// flakyStorage fails the first uploads of every key.
+type flakyStorage struct {
+	mutex    sync.Mutex
+	failures int
+	attempts map[string]int
+}
+
+func (s *flakyStorage) Upload(ctx context.Context, key string, path string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.attempts[key]++
+	if s.attempts[key] <= s.failures {
+		return errors.New("connection reset")
+	}
+
+	return nil
+}
+
+func (s *flakyStorage) URL(key string) string {
+	return "mem://" + key
+}
+
+func TestUploaderRetries(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		failures int
+		success  bool
+	}{
+		{"succeeds after retries", uploadAttempts - 1, true},
+		{"gives up", uploadAttempts, false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			storage := &flakyStorage{failures: test.failures, attempts: make(map[string]int)}
+			u := newUploader(context.Background(), storage)
+			u.retryDelay = 0
+
+			var result error
+			u.upload("key", "path", func(err error) { result = err })
+			u.wait()
+
+			if (result == nil) != test.success {
+				t.Errorf("expected success to be %v, got %v", test.success, result)
+			}
+
+			expected := min(test.failures+1, uploadAttempts)
+			if storage.attempts["key"] != expected {
+				t.Errorf("expected %d attempts, got %d", expected, storage.attempts["key"])
+			}
+		})
+	}
+}
+
+// blockingStorage blocks every upload until it is released.
+type blockingStorage struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingStorage) Upload(ctx context.Context, key string, path string) error {
+	s.started <- struct{}{}
+	<-s.release
+	return nil
+}
+
+func (s *blockingStorage) URL(key string) string {
+	return "mem://" + key
+}
+
+func TestUploaderGivesUpWaitingForSlot(t *testing.T) {
+	storage := &blockingStorage{started: make(chan struct{}, uploadConcurrency), release: make(chan struct{})}
+	ctx, cancel := context.WithCancelCause(context.Background())
+	u := newUploader(ctx, storage)
+
+	for range uploadConcurrency {
+		u.upload("busy", "path", func(error) {})
+	}
+
+	for range uploadConcurrency {
+		<-storage.started
+	}
+
+	cause := errors.New("interrupted")
+	results := make(chan error, 1)
+	u.upload("queued", "path", func(err error) { results <- err })
+	cancel(cause)
+
+	if err := <-results; !errors.Is(err, cause) {
+		t.Errorf("expected the queued upload to be given up on with %v, got %v", cause, err)
+	}
+
+	close(storage.release)
+	u.wait()
+}
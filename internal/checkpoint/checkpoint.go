@@ -0,0 +1,132 @@
+// Package checkpoint saves the progress of a run after each test case, so that
+// a later run can resume it from the first test case that did not pass.
+package checkpoint
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/microsoft/storm/pkg/storm/results"
+)
+
+// Name of the checkpoint file saved to the log directory by default.
+const FileName = "_checkpoint.json"
+
+// Version of the checkpoint format, bumped on incompatible changes.
+const version = 1
+
+// Checkpoint is the progress of a run of a scenario or helper.
+type Checkpoint struct {
+	Version int `json:"version"`
+
+	// Suite, registrant and arguments of the run.
+	Suite          string   `json:"suite"`
+	RegistrantType string   `json:"registrantType"`
+	Registrant     string   `json:"registrant"`
+	Args           []string `json:"args,omitempty"`
+
+	// Time at which the checkpoint was saved.
+	Time time.Time `json:"time"`
+
+	// Status of every test case of the run, in execution order.
+	TestCases []TestCase `json:"testCases"`
+
+	// Values of the shared state whose keys are persistent.
+	State []StateEntry `json:"state,omitempty"`
+}
+
+// TestCase is the status of a test case when the checkpoint was saved.
+type TestCase struct {
+	Name   string                 `json:"name"`
+	Status results.TestCaseStatus `json:"status"`
+	Reason string                 `json:"reason,omitempty"`
+
+	// Timings of the test case, zero if it did not start. For test cases
+	// that passed in an earlier run, those of that run.
+	StartTime time.Time     `json:"startTime"`
+	Duration  time.Duration `json:"duration"`
+}
+
+// StateEntry is a value of the shared state, serialized as JSON.
+type StateEntry struct {
+	Key      string          `json:"key"`
+	Producer string          `json:"producer"`
+	Value    json.RawMessage `json:"value"`
+}
+
+// Passed returns how many test cases at the start of the checkpoint passed,
+// provided they are named as the given test cases, in the same order.
+func (c *Checkpoint) Passed(names []string) int {
+	for i, testCase := range c.TestCases {
+		if i >= len(names) || testCase.Name != names[i] || testCase.Status != results.TestCaseStatusPassed {
+			return i
+		}
+	}
+
+	return len(c.TestCases)
+}
+
+// Load reads the checkpoint saved at the given path.
+func Load(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	var checkpoint Checkpoint
+	err = json.Unmarshal(data, &checkpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint '%s': %w", path, err)
+	}
+
+	if checkpoint.Version != version {
+		return nil, fmt.Errorf("checkpoint '%s' has version %d, expected %d", path, checkpoint.Version, version)
+	}
+
+	return &checkpoint, nil
+}
+
+// Save writes the checkpoint to the given path, creating its directory if
+// needed. The file is replaced atomically, so that a run killed while saving
+// leaves the previous checkpoint intact.
+func Save(path string, checkpoint Checkpoint) error {
+	checkpoint.Version = version
+	checkpoint.Time = time.Now()
+
+	data, err := json.MarshalIndent(checkpoint, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to generate checkpoint: %w", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Chmod(0644)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+
+	err = os.Rename(tmp.Name(), path)
+	if err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package checkpoint
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/microsoft/storm/pkg/storm/results"
+)
+
+func TestSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "runs", FileName)
+	saved := Checkpoint{
+		Registrant: "deploy",
+		TestCases: []TestCase{
+			{Name: "provision", Status: results.TestCaseStatusPassed, Duration: time.Minute},
+			{Name: "install", Status: results.TestCaseStatusPassed},
+			{Name: "verify", Status: results.TestCaseStatusFailed, Reason: "broken"},
+			{Name: "report", Status: results.TestCaseStatusPassed},
+		},
+	}
+
+	err := Save(path, saved)
+	if err != nil {
+		t.Fatalf("failed to save checkpoint: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("failed to load checkpoint: %v", err)
+	}
+
+	if loaded.Registrant != "deploy" || len(loaded.TestCases) != 4 || loaded.TestCases[0].Duration != time.Minute {
+		t.Errorf("unexpected checkpoint %+v", loaded)
+	}
+
+	for _, test := range []struct {
+		names    []string
+		expected int
+	}{
+		{[]string{"provision", "install", "verify", "report"}, 2},
+		{[]string{"provision", "configure", "install"}, 1},
+		{[]string{"install"}, 0},
+		{[]string{"provision"}, 1},
+	} {
+		if passed := loaded.Passed(test.names); passed != test.expected {
+			t.Errorf("expected %d test cases of %v to have passed, got %d", test.expected, test.names, passed)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"os"
 
 	"github.com/microsoft/storm/internal/cli/list"
+	"github.com/microsoft/storm/internal/cli/report"
 	"github.com/microsoft/storm/internal/cli/run"
 
 	"github.com/alecthomas/kong"
@@ -16,11 +17,12 @@ type GlobalOpts struct {
 }
 
 type cli struct {
-	Global GlobalOpts      `embed:""`
-	List   list.ListCmd    `cmd:"" help:"List resources"`
-	Run    run.ScenarioCmd `cmd:"" help:"Run a specific scenario"`
-	Helper run.HelperCmd   `cmd:"" help:"Run a specific helper"`
-	Script run.ScriptCmd   `cmd:"" help:"Run a specific script"`
+	Global GlobalOpts       `embed:""`
+	List   list.ListCmd     `cmd:"" help:"List resources"`
+	Run    run.ScenarioCmd  `cmd:"" help:"Run a specific scenario"`
+	Helper run.HelperCmd    `cmd:"" help:"Run a specific helper"`
+	Script run.ScriptCmd    `cmd:"" help:"Run a specific script"`
+	Report report.ReportCmd `cmd:"" help:"Work with saved results"`
 }
 
 func ParseCommandLine(name string, scripts []any) (*kong.Context, GlobalOpts) {
@@ -0,0 +1,53 @@
+package report
+
+import (
+	"fmt"
+
+	"github.com/microsoft/storm/internal/reporter"
+	"github.com/microsoft/storm/pkg/storm/core"
+)
+
+type MergeCmd struct {
+	Files []string `arg:"" help:"Result files to merge, either JUnit XML or JSON results files." type:"existingfile"`
+	JUnit *string  `short:"j" help:"Produce merged JUnit XML output at the given path." type:"path"`
+	Json  *string  `short:"J" help:"Produce a merged JSON results file at the given path." type:"path"`
+	Html  *string  `short:"H" help:"Produce a merged HTML report at the given path." type:"path"`
+}
+
+func (cmd *MergeCmd) Run(suite core.SuiteContext) error {
+	log := suite.Logger()
+	log.Infof("Merging %d result files", len(cmd.Files))
+
+	runs, err := reporter.MergeResults(cmd.Files)
+	if err != nil {
+		return err
+	}
+
+	reporter.PrintMergedSummary(runs)
+
+	if cmd.JUnit != nil {
+		log.Infof("Producing merged JUnit XML output at '%s'", *cmd.JUnit)
+		err := reporter.WriteJUnitXML(*cmd.JUnit, runs)
+		if err != nil {
+			return fmt.Errorf("failed to produce JUnit XML at '%s': %w", *cmd.JUnit, err)
+		}
+	}
+
+	if cmd.Json != nil {
+		log.Infof("Producing merged JSON results at '%s'", *cmd.Json)
+		err := reporter.WriteJSON(*cmd.Json, runs)
+		if err != nil {
+			return fmt.Errorf("failed to produce JSON results at '%s': %w", *cmd.Json, err)
+		}
+	}
+
+	if cmd.Html != nil {
+		log.Infof("Producing merged HTML report at '%s'", *cmd.Html)
+		err := reporter.WriteHTML(*cmd.Html, runs)
+		if err != nil {
+			return fmt.Errorf("failed to produce HTML report at '%s': %w", *cmd.Html, err)
+		}
+	}
+
+	return nil
+}
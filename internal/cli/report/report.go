@@ -0,0 +1,6 @@
+package report
+
+type ReportCmd struct {
+	Show  ShowCmd  `cmd:"" help:"Show the report of a saved run"`
+	Merge MergeCmd `cmd:"" help:"Merge result files from multiple runs into a single report"`
+}
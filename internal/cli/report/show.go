@@ -0,0 +1,89 @@
+package report
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/microsoft/storm/internal/reporter"
+	"github.com/microsoft/storm/pkg/storm/core"
+)
+
+type ShowCmd struct {
+	File    string  `arg:"" help:"Result file to show, either a JUnit XML or a JSON results file." type:"existingfile"`
+	Section string  `short:"s" help:"Section of the console report to print." enum:"all,summary,failures,result,none" default:"all"`
+	LogDir  *string `short:"l" help:"Optional directory to save logs to. Will be created if it does not exist." type:"path"`
+	JUnit   *string `short:"j" help:"Produce JUnit XML output at the given path." type:"path"`
+	Json    *string `short:"J" help:"Produce a JSON results file at the given path." type:"path"`
+	Html    *string `short:"H" help:"Produce an HTML report at the given path." type:"path"`
+}
+
+func (cmd *ShowCmd) Run(suite core.SuiteContext) error {
+	log := suite.Logger()
+	log.Infof("Loading results from '%s'", cmd.File)
+
+	runs, err := reporter.ReadResults(cmd.File)
+	if err != nil {
+		return err
+	}
+
+	if cmd.LogDir != nil {
+		log.Infof("Saving logs to '%s'", *cmd.LogDir)
+		err := os.MkdirAll(*cmd.LogDir, 0755)
+		if err != nil {
+			return fmt.Errorf("failed to create log directory '%s': %w", *cmd.LogDir, err)
+		}
+	}
+
+	for _, run := range runs {
+		rep := reporter.NewTestReporter(run, suite.AzureDevops())
+		switch cmd.Section {
+		case "all":
+			rep.PrintReport()
+		case "summary":
+			rep.PrintShortReport()
+		case "failures":
+			rep.PrintFailureReport()
+		case "result":
+			rep.PrintFinalResult()
+		}
+
+		if cmd.LogDir != nil {
+			err := reporter.NewLogReporter(*cmd.LogDir).RunFinished(run)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Files holding several runs, such as merged reports, get an aggregated
+	// summary at the end.
+	if len(runs) > 1 && cmd.Section != "none" {
+		reporter.PrintMergedSummary(runs)
+	}
+
+	if cmd.JUnit != nil {
+		log.Infof("Producing JUnit XML output at '%s'", *cmd.JUnit)
+		err := reporter.WriteJUnitXML(*cmd.JUnit, runs)
+		if err != nil {
+			return fmt.Errorf("failed to produce JUnit XML at '%s': %w", *cmd.JUnit, err)
+		}
+	}
+
+	if cmd.Json != nil {
+		log.Infof("Producing JSON results at '%s'", *cmd.Json)
+		err := reporter.WriteJSON(*cmd.Json, runs)
+		if err != nil {
+			return fmt.Errorf("failed to produce JSON results at '%s': %w", *cmd.Json, err)
+		}
+	}
+
+	if cmd.Html != nil {
+		log.Infof("Producing HTML report at '%s'", *cmd.Html)
+		err := reporter.WriteHTML(*cmd.Html, runs)
+		if err != nil {
+			return fmt.Errorf("failed to produce HTML report at '%s': %w", *cmd.Html, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package run
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/microsoft/storm/internal/artifacts"
+	"github.com/microsoft/storm/internal/storage"
+	stormartifacts "github.com/microsoft/storm/pkg/storm/artifacts"
+)
+
+// ArtifactFlags holds the flags controlling how artifacts are stored, shared
+// by scenarios and helpers.
+type ArtifactFlags struct {
+	MaxArtifactSize     ByteSize   `help:"Maximum size of a single artifact, e.g. 100M. Larger files are truncated, larger directories are rejected." placeholder:"SIZE"`
+	MaxArtifactsSize    ByteSize   `help:"Maximum total size of all artifacts of the run, e.g. 2G. Artifacts that no longer fit are truncated or rejected." placeholder:"SIZE"`
+	ArtifactCompression string     `help:"Compression applied to published artifacts." enum:"none,gzip,zstd" default:"none"`
+	BundleArtifacts     bool       `help:"Bundle the artifacts of each test case into a tarball once it has finished."`
+	DevopsArtifacts     string     `help:"How artifacts are announced to Azure DevOps once each test case has finished: uploaded to a pipeline artifact, or attached to the run's logs. Requires the Azure DevOps integration." enum:"none,artifact,attachment" default:"none"`
+	DevopsArtifactName  string     `help:"Name of the pipeline artifact artifacts are uploaded to with --devops-artifacts=artifact. Defaults to the name of the suite." placeholder:"NAME"`
+	ArtifactStorage     StorageURL `help:"Storage to upload artifacts to once each test case has finished: a path, file://, s3://bucket/prefix or azblob://account/container/prefix URL." placeholder:"URL" env:"STORM_ARTIFACT_STORAGE"`
+}
+
+// Options returns the artifact options described by the flags.
+func (f ArtifactFlags) Options() artifacts.Options {
+	return artifacts.Options{
+		MaxArtifactSize: int64(f.MaxArtifactSize),
+		MaxTotalSize:    int64(f.MaxArtifactsSize),
+		Compression:     artifacts.Compression(f.ArtifactCompression),
+		Bundle:          f.BundleArtifacts,
+
+		DevopsUpload:       artifacts.DevopsUpload(f.DevopsArtifacts),
+		DevopsArtifactName: f.DevopsArtifactName,
+
+		Storage: f.ArtifactStorage.Storage,
+	}
+}
+
+// StorageURL is an artifact storage, parsed from its URL.
+type StorageURL struct {
+	stormartifacts.Storage
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *StorageURL) UnmarshalText(text []byte) error {
+	storage, err := storage.Parse(string(text))
+	if err != nil {
+		return err
+	}
+
+	s.Storage = storage
+	return nil
+}
+
+// ByteSize is a size in bytes, parsed from a number with an optional binary
+// unit suffix: K, M, G or T.
+type ByteSize int64
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *ByteSize) UnmarshalText(text []byte) error {
+	value := strings.ToUpper(strings.TrimSpace(string(text)))
+	value = strings.TrimSuffix(strings.TrimSuffix(value, "B"), "I")
+
+	multiplier := int64(1)
+	if i := strings.IndexAny(value, "KMGT"); i >= 0 && i == len(value)-1 {
+		multiplier = int64(1) << (10 * (strings.IndexByte("KMGT", value[i]) + 1))
+		value = value[:i]
+	}
+
+	number, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || number < 0 {
+		return fmt.Errorf("invalid size '%s', expected a number with an optional K, M, G or T suffix", text)
+	}
+
+	if number > math.MaxInt64/multiplier {
+		return fmt.Errorf("size '%s' is too large", text)
+	}
+
+	*s = ByteSize(number * multiplier)
+	return nil
+}
@@ -0,0 +1,36 @@
+package run
+
+import "testing"
+
+func TestByteSizeUnmarshalText(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected ByteSize
+		valid    bool
+	}{
+		{text: "123", expected: 123, valid: true},
+		{text: "2k", expected: 2 << 10, valid: true},
+		{text: "100M", expected: 100 << 20, valid: true},
+		{text: "1GiB", expected: 1 << 30, valid: true},
+		{text: " 3TB ", expected: 3 << 40, valid: true},
+		{text: "8388607T", expected: 8388607 << 40, valid: true},
+		{text: "8388608T"},
+		{text: "9223372036854775808"},
+		{text: "-1K"},
+		{text: "1X"},
+		{text: ""},
+	}
+
+	for _, test := range tests {
+		var size ByteSize
+		err := size.UnmarshalText([]byte(test.text))
+		switch {
+		case test.valid && err != nil:
+			t.Errorf("expected '%s' to be valid, got %v", test.text, err)
+		case test.valid && size != test.expected:
+			t.Errorf("expected '%s' to be %d bytes, got %d", test.text, test.expected, size)
+		case !test.valid && err == nil:
+			t.Errorf("expected '%s' to be invalid, got %d bytes", test.text, size)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package run
+
+import "github.com/microsoft/storm/internal/runner"
+
+// CheckpointFlags holds the flags controlling the checkpoint saved after each
+// test case and resuming from it, shared by scenarios and helpers.
+type CheckpointFlags struct {
+	Checkpoint *string `help:"Path to save the progress of the run to after each test case. Defaults to _checkpoint.json in the log directory." type:"path"`
+	Resume     *string `help:"Resume the run saved to the given checkpoint: the test cases that passed at its start are not run again and are reported as previously passed." type:"path" xor:"previous-run"`
+}
+
+// Options returns the checkpoint options described by the flags.
+func (f CheckpointFlags) Options() runner.CheckpointOptions {
+	return runner.CheckpointOptions{
+		Path:   f.Checkpoint,
+		Resume: f.Resume,
+	}
+}
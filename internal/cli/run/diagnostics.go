@@ -0,0 +1,9 @@
+package run
+
+import "time"
+
+// DiagnosticsFlags holds the flags controlling the diagnostics collected after
+// failed test cases, shared by scenarios and helpers.
+type DiagnosticsFlags struct {
+	DiagnosticsBudget time.Duration `help:"How long the diagnostics collectors of the suite may take in total after a test case failed or errored, 0 to disable them." default:"2m" env:"STORM_DIAGNOSTICS_BUDGET"`
+}
@@ -0,0 +1,18 @@
+package run
+
+import "github.com/microsoft/storm/internal/runner"
+
+// RerunFlags holds the flags selecting the test cases to run again from an
+// earlier run, shared by scenarios and helpers.
+type RerunFlags struct {
+	RerunFailed       *string `help:"Only run the test cases that failed or errored in the given JSON results or JUnit XML file. They are reported as reruns." type:"path" xor:"previous-run"`
+	RerunDependencies bool    `help:"With --rerun-failed, also run the test cases before the ones that failed, which they may depend on."`
+}
+
+// Options returns the rerun options described by the flags.
+func (f RerunFlags) Options() runner.RerunOptions {
+	return runner.RerunOptions{
+		Results:      f.RerunFailed,
+		Dependencies: f.RerunDependencies,
+	}
+}
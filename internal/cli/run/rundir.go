@@ -0,0 +1,20 @@
+package run
+
+import "github.com/microsoft/storm/internal/runner"
+
+// RunDirFlags holds the flags controlling the run directory created when no
+// log directory is given, shared by scenarios and helpers.
+type RunDirFlags struct {
+	RunsDir  string `help:"Directory to create a timestamped run directory in when no log directory is given. Defaults to the user's cache directory." env:"STORM_RUNS_DIR"`
+	KeepRuns int    `help:"Number of run directories to keep in the runs directory, 0 to keep all of them." default:"10" env:"STORM_KEEP_RUNS"`
+	NoRunDir bool   `help:"Do not create a run directory when no log directory is given; logs and artifacts are then dropped."`
+}
+
+// Options returns the run directory options described by the flags.
+func (f RunDirFlags) Options() runner.RunDirOptions {
+	return runner.RunDirOptions{
+		Disabled: f.NoRunDir,
+		Root:     f.RunsDir,
+		Keep:     f.KeepRuns,
+	}
+}
@@ -6,11 +6,21 @@ import (
 )
 
 type ScenarioCmd struct {
-	Scenario     string   `arg:"" name:"scenario" help:"Name of the scenario to run"`
-	Watch        bool     `short:"w" help:"Watch the output of the scenario live"`
-	LogDir       *string  `short:"l" help:"Optional directory to save logs to. Will be created if it does not exist." type:"path"`
-	JUnit        *string  `short:"j" help:"Produce JUnit XML output at the given path." type:"path"`
-	ScenarioArgs []string `arg:"" passthrough:"all" help:"Arguments to pass to the scenario, you may use '--' to force passthrough." optional:""`
+	Scenario                  string  `arg:"" name:"scenario" help:"Name of the scenario to run"`
+	Watch                     bool    `short:"w" help:"Watch the output of the scenario live"`
+	LogDir                    *string `short:"l" help:"Directory to save logs and artifacts to. Will be created if it does not exist. Defaults to a new run directory, see --runs-dir." type:"path"`
+	JUnit                     *string `short:"j" help:"Produce JUnit XML output at the given path." type:"path"`
+	Json                      *string `short:"J" help:"Produce a JSON results file at the given path." type:"path"`
+	OutputHead                int     `help:"Number of lines kept in memory from the start of each test case's output for reports. The full output is always saved to the log directory." default:"1000"`
+	OutputTail                int     `help:"Number of lines kept in memory from the end of each test case's output for reports." default:"5000"`
+	SkipCleanupOnSetupFailure bool    `help:"Do not run the cleanup when the setup fails. By default, the cleanup runs to release partially provisioned resources."`
+	ArtifactFlags             `embed:""`
+	RunDirFlags               `embed:""`
+	TimeLimitFlags            `embed:""`
+	DiagnosticsFlags          `embed:""`
+	CheckpointFlags           `embed:""`
+	RerunFlags                `embed:""`
+	ScenarioArgs              []string `arg:"" passthrough:"all" help:"Arguments to pass to the scenario, you may use '--' to force passthrough." optional:""`
 }
 
 func (cmd *ScenarioCmd) Run(suite core.SuiteContext) error {
@@ -19,5 +29,20 @@ func (cmd *ScenarioCmd) Run(suite core.SuiteContext) error {
 
 	scenario := suite.Scenario(cmd.Scenario)
 
-	return runner.RegisterAndRunTests(suite, scenario, cmd.ScenarioArgs, cmd.Watch, cmd.LogDir, cmd.JUnit)
+	return runner.RegisterAndRunTests(suite, scenario, cmd.ScenarioArgs, runner.RunOptions{
+		Watch:           cmd.Watch,
+		LogDir:          cmd.LogDir,
+		RunDir:          cmd.RunDirFlags.Options(),
+		JUnitPath:       cmd.JUnit,
+		JSONPath:        cmd.Json,
+		OutputHeadLines: cmd.OutputHead,
+		OutputTailLines: cmd.OutputTail,
+		Artifacts:       cmd.ArtifactFlags.Options(),
+		TimeLimits:      cmd.TimeLimitFlags.Options(),
+		Checkpoint:      cmd.CheckpointFlags.Options(),
+		Rerun:           cmd.RerunFlags.Options(),
+
+		SkipCleanupAfterSetupFailure: cmd.SkipCleanupOnSetupFailure,
+		DiagnosticsBudget:            cmd.DiagnosticsBudget,
+	})
 }
@@ -0,0 +1,24 @@
+package run
+
+import (
+	"time"
+
+	"github.com/microsoft/storm/internal/runner"
+)
+
+// TimeLimitFlags holds the flags controlling how long a run may last and how
+// it stops, shared by scenarios and helpers.
+type TimeLimitFlags struct {
+	MaxDuration    *time.Duration `help:"Maximum duration of the run, after which it is stopped to clean up and write the reports. Defaults to the scenario or helper's own limit, if any; 0 disables it." aliases:"deadline" env:"STORM_MAX_DURATION"`
+	CleanupReserve time.Duration  `help:"Time reserved at the end of the maximum duration for the cleanups and the reports. Defaults to the grace period, capped to half of the maximum duration."`
+	GracePeriod    time.Duration  `help:"How long the running test case and each cleanup are waited for after an interruption before giving up on them, 0 to wait indefinitely." default:"30s" env:"STORM_GRACE_PERIOD"`
+}
+
+// Options returns the time limit options described by the flags.
+func (f TimeLimitFlags) Options() runner.TimeLimitOptions {
+	return runner.TimeLimitOptions{
+		MaxDuration:    f.MaxDuration,
+		CleanupReserve: f.CleanupReserve,
+		GracePeriod:    f.GracePeriod,
+	}
+}
@@ -0,0 +1,39 @@
+package devops
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Escapes the value of a logging command property, as documented for Azure
+// DevOps logging commands.
+var propertyEscaper = strings.NewReplacer(
+	"%", "%AZP25",
+	"\r", "%0D",
+	"\n", "%0A",
+	";", "%3B",
+	"]", "%5D",
+)
+
+// Escapes the message of a logging command.
+var messageEscaper = strings.NewReplacer(
+	"%", "%AZP25",
+	"\r", "%0D",
+	"\n", "%0A",
+)
+
+// UploadArtifact uploads the file or directory at path to the folder of the
+// given pipeline artifact, creating the artifact if needed.
+func UploadArtifact(artifactName string, folder string, path string) {
+	fmt.Printf("##vso[artifact.upload containerfolder=%s;artifactname=%s]%s\n",
+		propertyEscaper.Replace(folder),
+		propertyEscaper.Replace(artifactName),
+		messageEscaper.Replace(path),
+	)
+}
+
+// UploadFile attaches the file at path to the logs of the current pipeline
+// run.
+func UploadFile(path string) {
+	fmt.Printf("##vso[task.uploadfile]%s\n", messageEscaper.Replace(path))
+}
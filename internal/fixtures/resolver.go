@@ -0,0 +1,90 @@
+package fixtures
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/microsoft/storm/pkg/storm/fixtures"
+)
+
+// Resolver gives a test case the values of the fixtures registered in the
+// suite, from the store of their scope. It implements fixtures.Provider.
+type Resolver struct {
+	fixtures  []*fixtures.Fixture
+	stores    map[fixtures.Scope]fixtures.Instances
+	requester fixtures.Requester
+	mutex     sync.Mutex
+	setupTime time.Duration
+}
+
+// NewResolver creates a resolver of the given fixtures for the given test
+// case, taking their values from the stores of their scopes.
+func NewResolver(registered []*fixtures.Fixture, stores map[fixtures.Scope]fixtures.Instances, requester fixtures.Requester) *Resolver {
+	return &Resolver{
+		fixtures:  registered,
+		stores:    stores,
+		requester: requester,
+	}
+}
+
+// Fixture implements fixtures.Provider.
+func (r *Resolver) Fixture(name string) (any, error) {
+	for _, fixture := range r.fixtures {
+		if fixture.Name() == name {
+			return r.get(fixture)
+		}
+	}
+
+	return nil, fmt.Errorf("no fixture named '%s' is registered", name)
+}
+
+// FixtureOfType implements fixtures.Provider.
+func (r *Resolver) FixtureOfType(typ reflect.Type) (any, error) {
+	var matching []*fixtures.Fixture
+	for _, fixture := range r.fixtures {
+		if fixture.Type().AssignableTo(typ) {
+			matching = append(matching, fixture)
+		}
+	}
+
+	switch len(matching) {
+	case 0:
+		return nil, fmt.Errorf("no fixture of type %s is registered", typ)
+	case 1:
+		return r.get(matching[0])
+	default:
+		names := make([]string, len(matching))
+		for i, fixture := range matching {
+			names[i] = fixture.Name()
+		}
+
+		return nil, fmt.Errorf("several fixtures of type %s are registered (%s), request one by name", typ, strings.Join(names, ", "))
+	}
+}
+
+// SetupTime returns the time spent setting up the fixtures requested through
+// the resolver.
+func (r *Resolver) SetupTime() time.Duration {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.setupTime
+}
+
+func (r *Resolver) get(fixture *fixtures.Fixture) (any, error) {
+	store, ok := r.stores[fixture.Scope()]
+	if !ok {
+		return nil, fmt.Errorf("fixture '%s' has an unknown scope", fixture.Name())
+	}
+
+	value, setup, err := store.Get(fixture, r.requester)
+
+	r.mutex.Lock()
+	r.setupTime += setup
+	r.mutex.Unlock()
+
+	return value, err
+}
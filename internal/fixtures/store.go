@@ -0,0 +1,158 @@
+package fixtures
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/microsoft/storm/pkg/storm/fixtures"
+	"github.com/microsoft/storm/pkg/storm/results"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Store holds the values of the fixtures of one scope, set up the first time
+// they are requested and torn down together. It implements
+// fixtures.Instances.
+type Store struct {
+	scope     fixtures.Scope
+	ctx       context.Context
+	logger    *logrus.Entry
+	mutex     sync.Mutex
+	instances []*instance
+}
+
+type instance struct {
+	fixture *fixtures.Fixture
+	value   any
+	err     error
+	result  results.FixtureResult
+}
+
+// fixtureContext implements fixtures.Context.
+type fixtureContext struct {
+	ctx    context.Context
+	logger *logrus.Entry
+}
+
+func (c fixtureContext) Context() context.Context {
+	return c.ctx
+}
+
+func (c fixtureContext) Logger() *logrus.Entry {
+	return c.logger
+}
+
+// NewStore creates a store for the fixtures of the given scope. The setup of
+// the fixtures is given the context, which should end with the scope; their
+// teardown logs with the given logger.
+func NewStore(scope fixtures.Scope, ctx context.Context, logger *logrus.Entry) *Store {
+	return &Store{
+		scope:  scope,
+		ctx:    ctx,
+		logger: logger,
+	}
+}
+
+// Get implements fixtures.Instances. Fixtures are set up one at a time.
+func (s *Store) Get(fixture *fixtures.Fixture, requester fixtures.Requester) (any, time.Duration, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, inst := range s.instances {
+		if inst.fixture == fixture {
+			return inst.value, 0, inst.err
+		}
+	}
+
+	requester.Logger().Infof("Setting up %s fixture '%s'", s.scope, fixture.Name())
+
+	start := time.Now()
+	value, err := catchPanic(func() (any, error) {
+		return fixture.Setup(fixtureContext{ctx: s.ctx, logger: requester.Logger()})
+	})
+	elapsed := time.Since(start)
+
+	inst := &instance{
+		fixture: fixture,
+		value:   value,
+		result: results.FixtureResult{
+			Name:          fixture.Name(),
+			Scope:         s.scope.String(),
+			RequestedBy:   requester.Name(),
+			SetupDuration: elapsed,
+		},
+	}
+
+	if err != nil {
+		inst.err = fmt.Errorf("failed to set up fixture '%s': %w", fixture.Name(), err)
+		inst.result.SetupError = err.Error()
+	}
+
+	s.instances = append(s.instances, inst)
+	return inst.value, elapsed, inst.err
+}
+
+// Results implements fixtures.Instances.
+func (s *Store) Results() []results.FixtureResult {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	list := make([]results.FixtureResult, len(s.instances))
+	for i, inst := range s.instances {
+		list[i] = inst.result
+	}
+
+	return list
+}
+
+// Teardown tears down the fixtures that were set up, in reverse order. It
+// returns the errors of the teardowns, which are also logged and recorded in
+// the results.
+func (s *Store) Teardown() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// The teardown must release the resources even when the scope was
+	// cancelled.
+	ctx := fixtureContext{ctx: context.WithoutCancel(s.ctx), logger: s.logger}
+
+	var errs []error
+	for _, inst := range slices.Backward(s.instances) {
+		if inst.err != nil || inst.result.TornDown {
+			continue
+		}
+
+		s.logger.Debugf("Tearing down %s fixture '%s'", s.scope, inst.fixture.Name())
+
+		start := time.Now()
+		_, err := catchPanic(func() (any, error) {
+			return nil, inst.fixture.Teardown(ctx, inst.value)
+		})
+		inst.result.TornDown = true
+		inst.result.TeardownDuration = time.Since(start)
+
+		if err != nil {
+			inst.result.TeardownError = err.Error()
+			err = fmt.Errorf("failed to tear down fixture '%s': %w", inst.fixture.Name(), err)
+			s.logger.Error(err)
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// catchPanic runs f, turning panics into errors.
+func catchPanic(f func() (any, error)) (value any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic occurred: %v", r)
+		}
+	}()
+
+	return f()
+}
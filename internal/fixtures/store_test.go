@@ -0,0 +1,79 @@
+package fixtures
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/microsoft/storm/pkg/storm/fixtures"
+
+	"github.com/sirupsen/logrus"
+)
+
+type testRequester string
+
+func (r testRequester) Name() string { return string(r) }
+
+func (r testRequester) Logger() *logrus.Entry { return logrus.NewEntry(logrus.New()) }
+
+func TestStoreSetsUpOnceAndTearsDownInReverse(t *testing.T) {
+	var events []string
+	newFixture := func(name string, teardownErr error) *fixtures.Fixture {
+		return fixtures.New(name, fixtures.ScopeScenario,
+			func(fixtures.Context) (string, error) {
+				events = append(events, "setup "+name)
+				return name, nil
+			},
+			func(_ fixtures.Context, value string) error {
+				events = append(events, "teardown "+value)
+				return teardownErr
+			},
+		)
+	}
+
+	first := newFixture("first", nil)
+	second := newFixture("second", errors.New("stuck"))
+	store := NewStore(fixtures.ScopeScenario, context.Background(), logrus.NewEntry(logrus.New()))
+
+	for _, fixture := range []*fixtures.Fixture{first, second, first} {
+		_, _, err := store.Get(fixture, testRequester("test"))
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+
+	if err := store.Teardown(); err == nil {
+		t.Fatalf("expected the teardown error to be returned")
+	}
+
+	expected := []string{"setup first", "setup second", "teardown second", "teardown first"}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("expected events %v, got %v", expected, events)
+	}
+
+	results := store.Results()
+	if len(results) != 2 || results[1].TeardownError == "" || !results[0].TornDown {
+		t.Errorf("unexpected results %+v", results)
+	}
+}
+
+func TestResolverByType(t *testing.T) {
+	store := NewStore(fixtures.ScopeTestCase, context.Background(), logrus.NewEntry(logrus.New()))
+	stores := map[fixtures.Scope]fixtures.Instances{fixtures.ScopeTestCase: store}
+	setup := func(fixtures.Context) (int, error) { return 42, nil }
+
+	resolver := NewResolver([]*fixtures.Fixture{fixtures.New("answer", fixtures.ScopeTestCase, setup, nil)}, stores, testRequester("test"))
+	value, err := resolver.FixtureOfType(reflect.TypeFor[int]())
+	if err != nil || value != 42 {
+		t.Errorf("expected 42, got %v, %v", value, err)
+	}
+
+	resolver = NewResolver([]*fixtures.Fixture{
+		fixtures.New("a", fixtures.ScopeTestCase, setup, nil),
+		fixtures.New("b", fixtures.ScopeTestCase, setup, nil),
+	}, stores, testRequester("test"))
+	if _, err := resolver.FixtureOfType(reflect.TypeFor[int]()); err == nil {
+		t.Errorf("expected an ambiguous type to be rejected")
+	}
+}
@@ -0,0 +1,96 @@
+package reporter
+
+import (
+	"fmt"
+
+	"github.com/microsoft/storm/pkg/storm/core"
+	"github.com/microsoft/storm/pkg/storm/results"
+)
+
+// ConsoleReporter prints the summary, the failure report and the final result
+// of a run to the console once it has finished.
+type ConsoleReporter struct {
+	core.BaseReporter
+	azureDevops bool
+}
+
+func NewConsoleReporter(azureDevops bool) *ConsoleReporter {
+	return &ConsoleReporter{
+		azureDevops: azureDevops,
+	}
+}
+
+// RunFinished implements core.Reporter.
+func (r *ConsoleReporter) RunFinished(result results.RunResult) error {
+	NewTestReporter(result, r.azureDevops).PrintReport()
+	return nil
+}
+
+// JUnitReporter writes a JUnit XML report to the given path once a run has
+// finished.
+type JUnitReporter struct {
+	core.BaseReporter
+	path string
+}
+
+func NewJUnitReporter(path string) *JUnitReporter {
+	return &JUnitReporter{
+		path: path,
+	}
+}
+
+// RunFinished implements core.Reporter.
+func (r *JUnitReporter) RunFinished(result results.RunResult) error {
+	err := NewTestReporter(result, false).ProduceJUnitXML(r.path)
+	if err != nil {
+		return fmt.Errorf("failed to produce JUnit XML at '%s': %w", r.path, err)
+	}
+
+	return nil
+}
+
+// LogReporter saves the output of every test case to a file in the given
+// directory once a run has finished.
+type LogReporter struct {
+	core.BaseReporter
+	dir string
+}
+
+func NewLogReporter(dir string) *LogReporter {
+	return &LogReporter{
+		dir: dir,
+	}
+}
+
+// RunFinished implements core.Reporter.
+func (r *LogReporter) RunFinished(result results.RunResult) error {
+	err := NewTestReporter(result, false).SaveLogs(r.dir)
+	if err != nil {
+		return fmt.Errorf("failed to save logs to '%s': %w", r.dir, err)
+	}
+
+	return nil
+}
+
+// JSONReporter writes a JSON results file to the given path once a run has
+// finished.
+type JSONReporter struct {
+	core.BaseReporter
+	path string
+}
+
+func NewJSONReporter(path string) *JSONReporter {
+	return &JSONReporter{
+		path: path,
+	}
+}
+
+// RunFinished implements core.Reporter.
+func (r *JSONReporter) RunFinished(result results.RunResult) error {
+	err := WriteJSON(r.path, []results.RunResult{result})
+	if err != nil {
+		return fmt.Errorf("failed to produce JSON results at '%s': %w", r.path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package reporter
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/microsoft/storm/pkg/storm/results"
+	"github.com/microsoft/storm/pkg/storm/utils"
+)
+
+//go:embed templates/report.html
+var htmlTemplateSource string
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"join":     strings.Join,
+	"clean":    utils.RemoveAllANSI,
+	"round":    func(d time.Duration) time.Duration { return d.Round(time.Millisecond) },
+	"offset":   relativeTime,
+	"artifact": artifactURL,
+}).Parse(htmlTemplateSource))
+
+type htmlReport struct {
+	Title   string
+	Status  string
+	Summary string
+	Runs    []htmlRun
+}
+
+type htmlRun struct {
+	Info      results.RunInfo
+	Status    string
+	Summary   string
+	Duration  time.Duration
+	TestCases []results.TestCaseResult
+	Artifacts []results.Artifact
+}
+
+// WriteHTML writes the given runs to a standalone HTML report.
+func WriteHTML(filename string, runs []results.RunResult) error {
+	summary := NewSummary(runs...)
+	report := htmlReport{
+		Title:   fmt.Sprintf("Test report of %s", commonSuiteName(runs)),
+		Status:  summary.Status().String(),
+		Summary: summary.Summary(),
+		Runs:    make([]htmlRun, len(runs)),
+	}
+
+	for i, run := range runs {
+		runReporter := NewTestReporter(run, false)
+		report.Runs[i] = htmlRun{
+			Info:      run.RunInfo,
+			Status:    runReporter.Summary().Status().String(),
+			Summary:   runReporter.Summary().Summary(),
+			Duration:  run.Duration,
+			TestCases: runReporter.allResults(),
+			Artifacts: run.Artifacts,
+		}
+	}
+
+	var buffer bytes.Buffer
+	err := htmlTemplate.Execute(&buffer, report)
+	if err != nil {
+		return fmt.Errorf("failed to generate HTML report: %w", err)
+	}
+
+	err = os.WriteFile(filename, buffer.Bytes(), 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write HTML report to file: %w", err)
+	}
+
+	return nil
+}
+
+// artifactURL links to an artifact of the given run. Uploaded artifacts are
+// linked to their storage. Otherwise, artifacts of runs with a known log
+// directory are linked with file URLs, since the report may be saved anywhere.
+func artifactURL(run results.RunInfo, artifact results.Artifact) template.URL {
+	if artifact.URL != "" {
+		return template.URL(artifact.URL)
+	}
+
+	if run.LogDir == "" {
+		return template.URL(artifact.Location())
+	}
+
+	location := url.URL{
+		Scheme: "file",
+		Path:   filepath.ToSlash(filepath.Join(run.LogDir, filepath.FromSlash(artifact.Location()))),
+	}
+
+	return template.URL(location.String())
+}
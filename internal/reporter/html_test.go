@@ -0,0 +1,42 @@
+package reporter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/microsoft/storm/pkg/storm/results"
+)
+
+func TestWriteHTMLArtifacts(t *testing.T) {
+	run := results.RunResult{
+		RunInfo: results.RunInfo{
+			Suite:          "storm-test",
+			RegistrantType: "scenario",
+			Registrant:     "my-scenario",
+			LogDir:         "/logs",
+		},
+		TestCases: []results.TestCaseResult{
+			{
+				TestCaseInfo: results.TestCaseInfo{Name: "passing"},
+				Status:       results.TestCaseStatusPassed,
+				Artifacts: []results.Artifact{
+					{Name: "serial.log", Path: "passing/serial.log", Kind: results.ArtifactKindFile},
+				},
+			},
+		},
+	}
+
+	filename := filepath.Join(t.TempDir(), "report.html")
+	err := WriteHTML(filename, []results.RunResult{run})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	data, _ := os.ReadFile(filename)
+	link := `href="file:///logs/passing/serial.log"`
+	if !strings.Contains(string(data), link) {
+		t.Errorf("expected the report to contain %s", link)
+	}
+}
@@ -0,0 +1,34 @@
+package reporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/microsoft/storm/pkg/storm/results"
+)
+
+// WriteJSON writes the given runs to a JSON results file.
+func WriteJSON(filename string, runs []results.RunResult) error {
+	data, err := json.MarshalIndent(results.Report{Runs: runs}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to generate JSON results: %w", err)
+	}
+
+	err = os.WriteFile(filename, data, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write JSON results to file: %w", err)
+	}
+
+	return nil
+}
+
+func parseJSON(data []byte) ([]results.RunResult, error) {
+	var report results.Report
+	err := json.Unmarshal(data, &report)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JSON results file: %w", err)
+	}
+
+	return report.Runs, nil
+}
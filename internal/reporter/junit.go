@@ -2,8 +2,12 @@ package reporter
 
 import (
 	"bytes"
+	"encoding/xml"
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -11,98 +15,596 @@ import (
 	"github.com/jstemmer/go-junit-report/v2/junit"
 	log "github.com/sirupsen/logrus"
 
-	"github.com/microsoft/storm/internal/testmgr"
-	"github.com/microsoft/storm/pkg/storm/utils"
+	"github.com/microsoft/storm/pkg/storm/results"
 )
 
 func toSecondsStr(d time.Duration) string {
 	return strconv.FormatFloat(d.Seconds(), 'f', 6, 64)
 }
 
+const (
+	// JUnit error types used to mark the synthetic test cases produced for
+	// failed setup and cleanup steps, so they can be told apart when a JUnit
+	// file is read back.
+	junitSetupErrorType   = "SetupError"
+	junitCleanupErrorType = "CleanupError"
+
+	// JUnit error type used for test cases that panicked.
+	junitPanicErrorType = "Panic"
+)
+
+// Matches the line introducing the output of a setup or cleanup step in the
+// system-out of a JUnit test suite, with the name, status and duration of the
+// step.
+var junitStepHeaderRegex = regexp.MustCompile(`^=== (\S+): (\S+) in ([0-9.]+)s ===$`)
+
+// Matches the lines referencing artifacts in the output of a JUnit test case,
+// following the convention of the Jenkins JUnit attachments plugin.
+var junitAttachmentRegex = regexp.MustCompile(`^\[\[ATTACHMENT\|(.+)\]\]$`)
+
 func (tr *TestReporter) ProduceJUnitXML(filename string) error {
+	return WriteJUnitXML(filename, []results.RunResult{tr.result})
+}
+
+// WriteJUnitXML writes the given runs to a JUnit XML file, one test suite per
+// run.
+func WriteJUnitXML(filename string, runs []results.RunResult) error {
 	testSuites := junit.Testsuites{
-		Name: tr.testManager.Suite().Name(),
-		Time: toSecondsStr(tr.testManager.Duration()),
+		Name: commonSuiteName(runs),
+	}
+
+	var duration time.Duration
+	for i, run := range runs {
+		newSuite := newJUnitSuite(run)
+		newSuite.ID = i
+		testSuites.AddSuite(newSuite)
+		duration += run.Duration
+	}
+
+	testSuites.Time = toSecondsStr(duration)
+
+	var buffer bytes.Buffer
+	err := testSuites.WriteXML(&buffer)
+	if err != nil {
+		return fmt.Errorf("failed to generate JUnit XML: %w", err)
+	}
+
+	err = os.WriteFile(filename, buffer.Bytes(), 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write JUnit XML to file: %w", err)
+	}
+
+	return nil
+}
+
+// newJUnitSuite converts a run result into a JUnit test suite. Failed setup and
+// cleanup steps are added as synthetic test cases so that they show up in CI,
+// the output of the other steps is written to the system-out of the suite.
+func newJUnitSuite(result results.RunResult) junit.Testsuite {
+	suite := junit.Testsuite{
+		Name:     result.Registrant,
+		Time:     toSecondsStr(result.Duration),
+		Hostname: result.Hostname,
+	}
+
+	if !result.StartTime.IsZero() {
+		suite.SetTimestamp(result.StartTime)
+	}
+
+	suite.AddProperty("suite", result.Suite)
+	suite.AddProperty("registrant_type", result.RegistrantType)
+	for _, tag := range result.Tags {
+		suite.AddProperty("tag", tag)
+	}
+
+	for _, stagePath := range result.StagePaths {
+		suite.AddProperty("stage_path", stagePath)
+	}
+
+	for _, arg := range result.Args {
+		suite.AddProperty("arg", arg)
+	}
+
+	if result.LogDir != "" {
+		suite.AddProperty("log_dir", result.LogDir)
 	}
 
-	newSuite := junit.Testsuite{
-		Name: tr.testManager.Registrant().Name(),
-		Time: toSecondsStr(tr.testManager.Duration()),
+	classname := fmt.Sprintf("%s.%s.%s", result.Suite, result.RegistrantType, result.Registrant)
+
+	// Steps are marked with a property so that they can be told apart from
+	// test cases when they passed.
+	if result.Setup != nil {
+		suite.AddProperty("step", result.Setup.Name)
+	}
+
+	if result.Cleanup != nil {
+		suite.AddProperty("step", result.Cleanup.Name)
 	}
 
-	for _, testCase := range tr.testManager.TestCases() {
-		// Fill in basic properties
-		tc := junit.Testcase{
-			Name:   testCase.Name(),
-			Status: testCase.Status().String(),
+	// JUnit test cases have no properties, reruns are listed in the suite's.
+	for _, testCase := range result.TestCases {
+		if testCase.Rerun {
+			suite.AddProperty("rerun", testCase.Name)
 		}
+	}
 
-		// These properties only make sense if the test was actually run,
-		// otherwise they will be misleading.
-		if testCase.Status().Ran() {
-			tc.Time = toSecondsStr(testCase.RunTime())
-			tc.SystemOut = &junit.Output{
-				Data: utils.RemoveAllANSI(strings.Join(testCase.CollectedOutput(), "\n")),
-			}
+	var stepOutput []string
+	for _, step := range []*results.TestCaseResult{result.Setup, result.Cleanup} {
+		if step != nil && !step.Status.IsBad() {
+			stepOutput = append(stepOutput, newJUnitStepOutput(*step, result.LogDir))
 		}
+	}
 
-		// Now handle the various statuses
-		switch testCase.Status() {
-		case testmgr.TestCaseStatusPending:
-			log.Errorf("Test case %s is still pending, marking as skipped in JUnit report", testCase.Name())
-			tc.Skipped = &junit.Result{
-				Message: "Test case is still pending",
-				Type:    "Pending",
-			}
-		case testmgr.TestCaseStatusRunning:
-			log.Errorf("Test case %s is still running, marking as error in JUnit report", testCase.Name())
-			tc.Error = &junit.Result{
-				Message: "Test case is still running",
-				Type:    "Running",
-			}
-		case testmgr.TestCaseStatusNotRun:
-			tc.Skipped = &junit.Result{
-				Message: "Test case was not run",
-				Type:    "NotRun",
-			}
-		case testmgr.TestCaseStatusSkipped:
-			tc.Skipped = &junit.Result{
-				Message: testCase.Reason(),
-				Type:    "Skipped",
-			}
-		case testmgr.TestCaseStatusFailed:
-			tc.Failure = &junit.Result{
-				Message: testCase.Reason(),
-			}
-		case testmgr.TestCaseStatusError:
-			tc.Error = &junit.Result{
-				Message: testCase.Reason(),
+	if len(stepOutput) > 0 {
+		suite.SystemOut = &junit.Output{Data: strings.Join(stepOutput, "\n")}
+	}
+
+	if result.Setup != nil && result.Setup.Status.IsBad() {
+		suite.AddTestcase(newJUnitStepTestcase(classname, *result.Setup, result.LogDir, junitSetupErrorType))
+	}
+
+	for _, testCase := range result.TestCases {
+		suite.AddTestcase(newJUnitTestcase(classname, testCase, result.LogDir))
+	}
+
+	if result.Cleanup != nil && result.Cleanup.Status.IsBad() {
+		suite.AddTestcase(newJUnitStepTestcase(classname, *result.Cleanup, result.LogDir, junitCleanupErrorType))
+	}
+
+	return suite
+}
+
+// newJUnitTestcase converts the result of a test case into a JUnit test case.
+// Its artifacts are referenced in its system-out, located in the given log
+// directory.
+func newJUnitTestcase(classname string, testCase results.TestCaseResult, logDir string) junit.Testcase {
+	// Fill in basic properties. The status is written as in saved results,
+	// so that it can be read back.
+	status, _ := testCase.Status.MarshalText()
+	tc := junit.Testcase{
+		Name:      testCase.Name,
+		Classname: classname,
+		Status:    string(status),
+	}
+
+	// These properties only make sense if the test was actually run,
+	// otherwise they will be misleading.
+	if testCase.Status.Ran() {
+		tc.Time = toSecondsStr(testCase.Duration)
+		// Only what was written to stdout and stderr is included, log
+		// entries are in the saved log of the test case.
+		tc.SystemOut = newJUnitOutput(testCase.Output, results.OutputStreamStdout)
+		tc.SystemErr = newJUnitOutput(testCase.Output, results.OutputStreamStderr)
+		tc.SystemOut = withJUnitAttachments(tc.SystemOut, testCase.Artifacts, logDir)
+	}
+
+	// Now handle the various statuses
+	switch testCase.Status {
+	case results.TestCaseStatusPending:
+		log.Errorf("Test case %s is still pending, marking as skipped in JUnit report", testCase.Name)
+		tc.Skipped = &junit.Result{
+			Message: "Test case is still pending",
+			Type:    "Pending",
+		}
+	case results.TestCaseStatusRunning:
+		log.Errorf("Test case %s is still running, marking as error in JUnit report", testCase.Name)
+		tc.Error = &junit.Result{
+			Message: "Test case is still running",
+			Type:    "Running",
+		}
+	case results.TestCaseStatusNotRun:
+		tc.Skipped = &junit.Result{
+			Message: "Test case was not run",
+			Type:    "NotRun",
+			Data:    testCase.Reason,
+		}
+	case results.TestCaseStatusSkipped:
+		tc.Skipped = &junit.Result{
+			Message: testCase.Reason,
+			Type:    "Skipped",
+		}
+	case results.TestCaseStatusFailed:
+		tc.Failure = &junit.Result{
+			Message: testCase.Reason,
+		}
+	case results.TestCaseStatusError:
+		tc.Error = &junit.Result{
+			Message: testCase.Reason,
+		}
+
+		// Include the stack trace when the test case panicked.
+		if testCase.Stack != "" {
+			tc.Error.Type = junitPanicErrorType
+			tc.Error.Data = testCase.Stack
+		}
+	case results.TestCaseStatusPassed:
+		// No action needed
+	default:
+		log.Warnf("Test case %s has unknown status %v, marking as error in JUnit report", testCase.Name, testCase.Status)
+		tc.Error = &junit.Result{
+			Message: "Unknown test case status",
+			Type:    "InvalidStatus",
+		}
+	}
+
+	return tc
+}
+
+// newJUnitStepTestcase converts the result of a setup or cleanup step into a
+// test case, marking its error with the given type.
+func newJUnitStepTestcase(classname string, step results.TestCaseResult, logDir string, errorType string) junit.Testcase {
+	tc := newJUnitTestcase(classname, step, logDir)
+	if tc.Error != nil {
+		tc.Error.Type = errorType
+	}
+
+	return tc
+}
+
+// newJUnitStepOutput formats a setup or cleanup step that did not fail for the
+// system-out of the suite: a header with its name, status and duration,
+// followed by its output and the lines referencing its artifacts.
+func newJUnitStepOutput(step results.TestCaseResult, logDir string) string {
+	status, _ := step.Status.MarshalText()
+	lines := []string{fmt.Sprintf("=== %s: %s in %ss ===", step.Name, status, toSecondsStr(step.Duration))}
+	output := newJUnitOutput(step.Output,
+		results.OutputStreamStdout,
+		results.OutputStreamStderr,
+		results.OutputStreamLogrus,
+		results.OutputStreamSlog,
+		results.OutputStreamStorm,
+	)
+	output = withJUnitAttachments(output, step.Artifacts, logDir)
+	if output != nil {
+		lines = append(lines, output.Data)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// newJUnitOutput joins the captured lines written to any of the given streams
+// into a JUnit output block, or returns nil when there is nothing to output.
+func newJUnitOutput(output []results.OutputLine, streams ...results.OutputStream) *junit.Output {
+	lines := make([]string, 0, len(output))
+	for _, line := range output {
+		if slices.Contains(streams, line.Stream) {
+			lines = append(lines, FormatOutputLine(line))
+		}
+	}
+
+	if len(lines) == 0 {
+		return nil
+	}
+
+	return &junit.Output{
+		Data: strings.Join(lines, "\n"),
+	}
+}
+
+// withJUnitAttachments adds a line referencing each of the given artifacts,
+// located in the given log directory, to a JUnit output block.
+func withJUnitAttachments(output *junit.Output, artifacts []results.Artifact, logDir string) *junit.Output {
+	if len(artifacts) == 0 {
+		return output
+	}
+
+	lines := make([]string, 0, len(artifacts)+1)
+	if output != nil {
+		lines = append(lines, output.Data)
+	}
+
+	// Bundled artifacts share the same location, which is only referenced
+	// once.
+	seen := make(map[string]bool)
+	for _, artifact := range artifacts {
+		location := artifact.Location()
+		if location == "" || seen[location] {
+			continue
+		}
+
+		seen[location] = true
+		lines = append(lines, fmt.Sprintf("[[ATTACHMENT|%s]]", filepath.Join(logDir, filepath.FromSlash(location))))
+	}
+
+	return &junit.Output{
+		Data: strings.Join(lines, "\n"),
+	}
+}
+
+// ReadJUnitXML reads the runs stored in a JUnit XML file. JUnit files produced
+// by storm are read back faithfully, files from other tools are read on a best
+// effort basis. Since JUnit keeps stdout and stderr apart, the order in which
+// the lines of both streams were written is only recovered when the lines are
+// timestamped.
+func ReadJUnitXML(filename string) ([]results.RunResult, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JUnit XML file: %w", err)
+	}
+
+	return parseJUnitXML(data)
+}
+
+func parseJUnitXML(data []byte) ([]results.RunResult, error) {
+	var testSuites junit.Testsuites
+	err := xml.Unmarshal(data, &testSuites)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JUnit XML file: %w", err)
+	}
+
+	runs := make([]results.RunResult, 0, len(testSuites.Suites))
+	for _, suite := range testSuites.Suites {
+		run, err := runFromJUnitSuite(testSuites.Name, suite)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JUnit test suite '%s': %w", suite.Name, err)
+		}
+
+		runs = append(runs, run)
+	}
+
+	return runs, nil
+}
+
+func runFromJUnitSuite(suiteName string, suite junit.Testsuite) (results.RunResult, error) {
+	run := results.RunResult{
+		RunInfo: results.RunInfo{
+			Suite:          suiteName,
+			RegistrantType: "unknown",
+			Registrant:     suite.Name,
+			Hostname:       suite.Hostname,
+		},
+		TestCases: make([]results.TestCaseResult, 0, len(suite.Testcases)),
+	}
+
+	steps := make(map[string]bool)
+	reruns := make(map[string]bool)
+	if suite.Properties != nil {
+		for _, property := range *suite.Properties {
+			switch property.Name {
+			case "log_dir":
+				run.LogDir = property.Value
+			case "suite":
+				run.Suite = property.Value
+			case "registrant_type":
+				run.RegistrantType = property.Value
+			case "tag":
+				run.Tags = append(run.Tags, property.Value)
+			case "stage_path":
+				run.StagePaths = append(run.StagePaths, property.Value)
+			case "arg":
+				run.Args = append(run.Args, property.Value)
+			case "step":
+				steps[property.Value] = true
+			case "rerun":
+				reruns[property.Value] = true
 			}
-		case testmgr.TestCaseStatusPassed:
-			// No action needed
-		default:
-			log.Warnf("Test case %s has unknown status %v, marking as error in JUnit report", testCase.Name(), testCase.Status())
-			tc.Error = &junit.Result{
-				Message: "Unknown test case status",
-				Type:    "InvalidStatus",
+		}
+	}
+
+	if suite.Timestamp != "" {
+		timestamp, err := time.Parse(time.RFC3339, suite.Timestamp)
+		if err != nil {
+			return run, fmt.Errorf("invalid timestamp: %w", err)
+		}
+
+		run.StartTime = timestamp
+	}
+
+	duration, err := fromSecondsStr(suite.Time)
+	if err != nil {
+		return run, err
+	}
+
+	run.Duration = duration
+
+	// Steps that did not fail are in the system-out of the suite.
+	if suite.SystemOut != nil {
+		for _, step := range stepsFromJUnit(suite.SystemOut, run.LogDir) {
+			switch {
+			case step.Name == "setup" && steps[step.Name]:
+				run.Setup = &step
+			case step.Name == "cleanup" && steps[step.Name]:
+				run.Cleanup = &step
 			}
 		}
+	}
+
+	for _, tc := range suite.Testcases {
+		testCase, err := testCaseFromJUnit(tc, run.LogDir)
+		if err != nil {
+			return run, fmt.Errorf("test case '%s': %w", tc.Name, err)
+		}
 
-		newSuite.AddTestcase(tc)
+		// Failed steps are recognized by their error type.
+		if tc.Error != nil && tc.Error.Type == junitSetupErrorType {
+			testCase.Index = -1
+			run.Setup = &testCase
+			continue
+		}
+
+		if tc.Error != nil && tc.Error.Type == junitCleanupErrorType {
+			testCase.Index = -1
+			run.Cleanup = &testCase
+			continue
+		}
+
+		testCase.Index = len(run.TestCases)
+		testCase.Rerun = reruns[tc.Name]
+		run.TestCases = append(run.TestCases, testCase)
+		run.RunInfo.TestCases = append(run.RunInfo.TestCases, testCase.Name)
 	}
 
-	testSuites.AddSuite(newSuite)
+	return run, nil
+}
 
-	var buffer bytes.Buffer
-	err := testSuites.WriteXML(&buffer)
+func testCaseFromJUnit(tc junit.Testcase, logDir string) (results.TestCaseResult, error) {
+	testCase := results.TestCaseResult{
+		TestCaseInfo: results.TestCaseInfo{
+			Name: tc.Name,
+		},
+	}
+
+	duration, err := fromSecondsStr(tc.Time)
 	if err != nil {
-		return fmt.Errorf("failed to generate JUnit XML: %w", err)
+		return testCase, err
 	}
 
-	err = os.WriteFile(filename, buffer.Bytes(), 0644)
+	testCase.Duration = duration
+
+	// Prefer the status written by storm, fall back to the JUnit elements for
+	// files produced by other tools.
+	status, err := results.ParseTestCaseStatus(tc.Status)
+	switch {
+	case err == nil:
+		testCase.Status = status
+	case tc.Error != nil:
+		testCase.Status = results.TestCaseStatusError
+	case tc.Failure != nil:
+		testCase.Status = results.TestCaseStatusFailed
+	case tc.Skipped != nil && tc.Skipped.Type == "NotRun":
+		testCase.Status = results.TestCaseStatusNotRun
+	case tc.Skipped != nil:
+		testCase.Status = results.TestCaseStatusSkipped
+	default:
+		testCase.Status = results.TestCaseStatusPassed
+	}
+
+	switch {
+	case tc.Error != nil:
+		testCase.Reason = tc.Error.Message
+		testCase.Stack = tc.Error.Data
+	case tc.Failure != nil:
+		testCase.Reason = tc.Failure.Message
+	case tc.Skipped != nil && tc.Skipped.Type == "NotRun":
+		testCase.Reason = tc.Skipped.Data
+	case tc.Skipped != nil:
+		testCase.Reason = tc.Skipped.Message
+	}
+
+	if tc.SystemOut != nil {
+		var output *junit.Output
+		output, testCase.Artifacts = artifactsFromJUnit(tc.SystemOut, logDir, tc.Name)
+		testCase.Output = append(testCase.Output, outputFromJUnit(output, results.OutputStreamStdout)...)
+	}
+
+	if tc.SystemErr != nil {
+		testCase.Output = append(testCase.Output, outputFromJUnit(tc.SystemErr, results.OutputStreamStderr)...)
+	}
+
+	// Restore the original interleaving of the streams when all lines are
+	// timestamped.
+	untimed := slices.ContainsFunc(testCase.Output, func(line results.OutputLine) bool {
+		return line.Time.IsZero()
+	})
+
+	if !untimed {
+		slices.SortStableFunc(testCase.Output, func(a, b results.OutputLine) int {
+			return a.Time.Compare(b.Time)
+		})
+	}
+
+	return testCase, nil
+}
+
+// stepsFromJUnit reads the steps written to the system-out of a JUnit test
+// suite by newJUnitStepOutput. Lines before the first step are ignored.
+func stepsFromJUnit(output *junit.Output, logDir string) []results.TestCaseResult {
+	var steps []results.TestCaseResult
+	var lines [][]string
+	for _, text := range strings.Split(output.Data, "\n") {
+		match := junitStepHeaderRegex.FindStringSubmatch(text)
+		if match == nil {
+			if len(lines) > 0 {
+				lines[len(lines)-1] = append(lines[len(lines)-1], text)
+			}
+			continue
+		}
+
+		status, err := results.ParseTestCaseStatus(match[2])
+		if err != nil {
+			continue
+		}
+
+		duration, _ := fromSecondsStr(match[3])
+		steps = append(steps, results.TestCaseResult{
+			TestCaseInfo: results.TestCaseInfo{Name: match[1], Index: -1},
+			Status:       status,
+			Duration:     duration,
+		})
+		lines = append(lines, nil)
+	}
+
+	for i := range steps {
+		if lines[i] == nil {
+			continue
+		}
+
+		var stepOutput *junit.Output
+		stepOutput, steps[i].Artifacts = artifactsFromJUnit(&junit.Output{Data: strings.Join(lines[i], "\n")}, logDir, steps[i].Name)
+		if stepOutput != nil {
+			steps[i].Output = outputFromJUnit(stepOutput, results.OutputStreamStdout)
+		}
+	}
+
+	return steps
+}
+
+// artifactsFromJUnit extracts the artifacts referenced in a JUnit output block,
+// returning the block without the lines referencing them. Artifacts located in
+// the given log directory are made relative to it, and named after their path
+// in the directory of the given test case.
+func artifactsFromJUnit(output *junit.Output, logDir string, testCase string) (*junit.Output, []results.Artifact) {
+	var artifacts []results.Artifact
+	var lines []string
+	for _, text := range strings.Split(output.Data, "\n") {
+		match := junitAttachmentRegex.FindStringSubmatch(text)
+		if match == nil {
+			lines = append(lines, text)
+			continue
+		}
+
+		path := match[1]
+		if rel, err := filepath.Rel(logDir, path); logDir != "" && err == nil && filepath.IsLocal(rel) {
+			path = rel
+		}
+
+		path = filepath.ToSlash(path)
+		artifacts = append(artifacts, results.Artifact{
+			Name: strings.TrimPrefix(path, testCase+"/"),
+			Path: path,
+			Kind: results.ArtifactKindFile,
+		})
+	}
+
+	if lines == nil {
+		return nil, artifacts
+	}
+
+	return &junit.Output{Data: strings.Join(lines, "\n")}, artifacts
+}
+
+func outputFromJUnit(output *junit.Output, fallback results.OutputStream) []results.OutputLine {
+	lines := make([]results.OutputLine, 0)
+	if output == nil {
+		return lines
+	}
+
+	for _, text := range strings.Split(output.Data, "\n") {
+		lines = append(lines, parseOutputLine(text, fallback))
+	}
+
+	return lines
+}
+
+func fromSecondsStr(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	seconds, err := strconv.ParseFloat(s, 64)
 	if err != nil {
-		return fmt.Errorf("failed to write JUnit XML to file: %w", err)
+		return 0, fmt.Errorf("invalid duration '%s': %w", s, err)
 	}
 
-	return nil
+	return time.Duration(seconds * float64(time.Second)), nil
 }
@@ -0,0 +1,182 @@
+package reporter
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/microsoft/storm/pkg/storm/results"
+)
+
+func TestJUnitRoundTrip(t *testing.T) {
+	start := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	run := results.RunResult{
+		RunInfo: results.RunInfo{
+			Suite:          "storm-test",
+			RegistrantType: "scenario",
+			Registrant:     "my-scenario",
+			Tags:           []string{"tag1", "tag2"},
+			StagePaths:     []string{"stage/path"},
+			Args:           []string{"--flag", "value"},
+			Hostname:       "host",
+			LogDir:         "/logs",
+			StartTime:      start,
+			TestCases:      []string{"passing", "panicking", "notRun"},
+		},
+		Duration: 3 * time.Second,
+		TestCases: []results.TestCaseResult{
+			{
+				TestCaseInfo: results.TestCaseInfo{Name: "passing", Index: 0},
+				Status:       results.TestCaseStatusPassed,
+				Duration:     time.Second,
+				Output: []results.OutputLine{
+					{Time: start.Add(time.Millisecond), Stream: results.OutputStreamStdout, Text: "hello"},
+					{Time: start.Add(2 * time.Millisecond), Stream: results.OutputStreamLogrus, Level: "info", Text: "INFO[0000] logged"},
+					{Time: start.Add(3 * time.Millisecond), Stream: results.OutputStreamStderr, Text: "world"},
+					{Time: start.Add(4 * time.Millisecond), Stream: results.OutputStreamStdout, Text: "again"},
+				},
+				Artifacts: []results.Artifact{
+					{Name: "dumps/serial.log", Path: "passing/dumps/serial.log", Kind: results.ArtifactKindFile},
+				},
+			},
+			{
+				TestCaseInfo: results.TestCaseInfo{Name: "panicking", Index: 1},
+				Status:       results.TestCaseStatusError,
+				Reason:       "panic occurred: oops",
+				Stack:        "goroutine 1 [running]:",
+				Duration:     2 * time.Second,
+				Rerun:        true,
+			},
+			{
+				TestCaseInfo: results.TestCaseInfo{Name: "notRun", Index: 2},
+				Status:       results.TestCaseStatusNotRun,
+				Reason:       "dependency failure",
+			},
+		},
+		Setup: &results.TestCaseResult{
+			TestCaseInfo: results.TestCaseInfo{Name: "setup", Index: -1},
+			Status:       results.TestCaseStatusPassed,
+			Duration:     time.Second,
+			Output: []results.OutputLine{
+				{Time: start, Stream: results.OutputStreamStdout, Text: "provisioned"},
+			},
+		},
+		Cleanup: &results.TestCaseResult{
+			TestCaseInfo: results.TestCaseInfo{Name: "cleanup", Index: -1},
+			Status:       results.TestCaseStatusError,
+			Reason:       "cleanup failed",
+		},
+	}
+
+	filename := filepath.Join(t.TempDir(), "junit.xml")
+	err := WriteJUnitXML(filename, []results.RunResult{run})
+	if err != nil {
+		t.Fatalf("failed to write JUnit XML: %v", err)
+	}
+
+	data, _ := os.ReadFile(filename)
+	if strings.Contains(string(data), `<testcase name="setup"`) || !strings.Contains(string(data), `<testcase name="cleanup"`) {
+		t.Errorf("expected only the failed cleanup to be written as a test case")
+	}
+
+	if !strings.Contains(string(data), "=== setup: PASS in 1.000000s ===") {
+		t.Errorf("expected the passed setup in the system-out of the suite")
+	}
+
+	runs, err := ReadJUnitXML(filename)
+	if err != nil {
+		t.Fatalf("failed to read JUnit XML: %v", err)
+	}
+
+	if len(runs) != 1 {
+		t.Fatalf("expected 1 run, got %d", len(runs))
+	}
+
+	// Log entries of test cases are not part of their system-err.
+	expected := run
+	expected.TestCases = slices.Clone(run.TestCases)
+	expected.TestCases[0].Output = slices.DeleteFunc(slices.Clone(run.TestCases[0].Output), func(line results.OutputLine) bool {
+		return line.Stream == results.OutputStreamLogrus
+	})
+
+	if !reflect.DeepEqual(runs[0], expected) {
+		t.Errorf("round trip mismatch:\nexpected: %+v\ngot:      %+v", expected, runs[0])
+	}
+}
+
+func TestNewSummaryAggregatesRuns(t *testing.T) {
+	passing := results.RunResult{
+		TestCases: []results.TestCaseResult{
+			{Status: results.TestCaseStatusPassed},
+			{Status: results.TestCaseStatusSkipped},
+		},
+	}
+
+	failedSetup := results.RunResult{
+		TestCases: []results.TestCaseResult{
+			{Status: results.TestCaseStatusNotRun},
+		},
+		Setup: &results.TestCaseResult{Status: results.TestCaseStatusError},
+	}
+
+	if status := NewSummary(passing).Status(); status != TestStatusOk {
+		t.Errorf("expected OK status for a passing run, got %s", status)
+	}
+
+	summary := NewSummary(passing, failedSetup)
+	if summary.Status() != TestStatusError {
+		t.Errorf("expected ERROR status when a setup failed, got %s", summary.Status())
+	}
+
+	expected := "setup errored: 1; skipped: 1; notrun: 1; passed: 1; total: 3"
+	if summary.Summary() != expected {
+		t.Errorf("expected summary '%s', got '%s'", expected, summary.Summary())
+	}
+}
+
+func TestNewSummaryCountsUnfinishedTestCases(t *testing.T) {
+	run := results.RunResult{
+		RunInfo: results.RunInfo{
+			Suite:      "storm-test",
+			Registrant: "my-scenario",
+			TestCases:  []string{"passing", "running", "pending"},
+		},
+		TestCases: []results.TestCaseResult{
+			{TestCaseInfo: results.TestCaseInfo{Name: "passing", Index: 0}, Status: results.TestCaseStatusPassed},
+			{TestCaseInfo: results.TestCaseInfo{Name: "running", Index: 1}, Status: results.TestCaseStatusRunning},
+			{TestCaseInfo: results.TestCaseInfo{Name: "pending", Index: 2}, Status: results.TestCaseStatusPending},
+		},
+	}
+
+	dir := t.TempDir()
+	writers := map[string]func(string, []results.RunResult) error{
+		"results.json": WriteJSON,
+		"junit.xml":    WriteJUnitXML,
+	}
+
+	for name, write := range writers {
+		filename := filepath.Join(dir, name)
+		if err := write(filename, []results.RunResult{run}); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+
+		runs, err := ReadResults(filename)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", name, err)
+		}
+
+		summary := NewSummary(runs...)
+		if summary.Status() != TestStatusError {
+			t.Errorf("%s: expected ERROR status for an unfinished run, got %s", name, summary.Status())
+		}
+
+		expected := "errored: 1; notrun: 1; passed: 1; total: 3"
+		if summary.Summary() != expected {
+			t.Errorf("%s: expected summary '%s', got '%s'", name, expected, summary.Summary())
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package reporter
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/microsoft/storm/pkg/storm/results"
+)
+
+// ReadResults reads the runs stored in a results file, which may either be a
+// JUnit XML file or a JSON results file. The format is detected from the
+// contents of the file.
+func ReadResults(filename string) ([]results.RunResult, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read results file '%s': %w", filename, err)
+	}
+
+	var runs []results.RunResult
+	switch trimmed := bytes.TrimSpace(data); {
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		runs, err = parseJSON(trimmed)
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		runs, err = parseJUnitXML(trimmed)
+	default:
+		return nil, fmt.Errorf("results file '%s' is neither JSON nor JUnit XML", filename)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to load results file '%s': %w", filename, err)
+	}
+
+	return runs, nil
+}
+
+// MergeResults reads all given results files and returns all their runs, in
+// the order the files were given.
+func MergeResults(filenames []string) ([]results.RunResult, error) {
+	merged := make([]results.RunResult, 0, len(filenames))
+	for _, filename := range filenames {
+		runs, err := ReadResults(filename)
+		if err != nil {
+			return nil, err
+		}
+
+		merged = append(merged, runs...)
+	}
+
+	return merged, nil
+}
+
+// commonSuiteName returns the name of the suite all runs belong to, or a
+// generic name when the runs come from different suites.
+func commonSuiteName(runs []results.RunResult) string {
+	if len(runs) == 0 {
+		return ""
+	}
+
+	name := runs[0].Suite
+	for _, run := range runs[1:] {
+		if run.Suite != name {
+			return "merged"
+		}
+	}
+
+	return name
+}
+
+// PrintMergedSummary prints the status of every run followed by the aggregated
+// result of all runs.
+func PrintMergedSummary(runs []results.RunResult) {
+	printSeparatorWithTitle(fmt.Sprintf("SUMMARY of %d runs", len(runs)))
+
+	names := make([]string, len(runs))
+	ljust := 0
+	for i, run := range runs {
+		names[i] = fmt.Sprintf("%s::%s::%s", run.Suite, run.RegistrantType, run.Registrant)
+		ljust = max(ljust, len(names[i]))
+	}
+
+	for i, run := range runs {
+		summary := NewSummary(run)
+		fmt.Printf(
+			"  %s%s: %s (%s)\n",
+			names[i],
+			strings.Repeat(".", ljust-len(names[i])),
+			summary.Status().StringColor(),
+			summary.Summary(),
+		)
+	}
+
+	summary := NewSummary(runs...)
+	printSeparatorWithTitle("RESULT")
+	fmt.Printf("%s: %s\n", summary.Status().StringColor(), summary.Summary())
+}
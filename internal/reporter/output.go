@@ -0,0 +1,90 @@
+package reporter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/microsoft/storm/pkg/storm/results"
+	"github.com/microsoft/storm/pkg/storm/utils"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Time format used for captured lines in saved logs and JUnit output.
+const outputTimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+// Matches a line produced by FormatOutputLine, capturing the time, the stream,
+// the optional level and the text of the line.
+var outputLineRegex = regexp.MustCompile(`^(\S+) \[([a-z]+)(?::([a-z]+))?\] (.*)$`)
+
+// FormatOutputLine formats a captured line for saved logs and JUnit output,
+// keeping the time it was captured at and its source:
+//
+//	2025-01-02T03:04:05.000000Z [logrus:info] INFO[0000] Hello!
+func FormatOutputLine(line results.OutputLine) string {
+	text := strings.TrimRightFunc(utils.RemoveAllANSI(line.Text), func(r rune) bool {
+		return r == ' ' || r == '\t' || r == '\r'
+	})
+
+	// Lines read back from files produced by other tools have no timestamp,
+	// they are kept as they are.
+	if line.Time.IsZero() {
+		return text
+	}
+
+	source := string(line.Stream)
+	if line.Level != "" {
+		source += ":" + line.Level
+	}
+
+	return fmt.Sprintf("%s [%s] %s", line.Time.Format(outputTimeFormat), source, text)
+}
+
+// parseOutputLine parses a line produced by FormatOutputLine. Lines in any
+// other format are kept as they are and attributed to the fallback stream.
+func parseOutputLine(text string, fallback results.OutputStream) results.OutputLine {
+	match := outputLineRegex.FindStringSubmatch(text)
+	if match == nil {
+		return results.OutputLine{Stream: fallback, Text: text}
+	}
+
+	timestamp, err := time.Parse(outputTimeFormat, match[1])
+	if err != nil {
+		return results.OutputLine{Stream: fallback, Text: text}
+	}
+
+	return results.OutputLine{
+		Time:   timestamp,
+		Stream: results.OutputStream(match[2]),
+		Level:  match[3],
+		Text:   match[4],
+	}
+}
+
+// OutputLineVisible returns whether a captured line should be shown on a
+// console configured with the given log level. Only log entries are filtered,
+// lines without a level are always shown.
+func OutputLineVisible(line results.OutputLine, level logrus.Level) bool {
+	if line.Level == "" {
+		return true
+	}
+
+	lineLevel, err := logrus.ParseLevel(line.Level)
+	if err != nil {
+		return true
+	}
+
+	return level >= lineLevel
+}
+
+// relativeTime formats the time of a captured line relative to the given start
+// time, e.g. "+1.234s". It returns an empty string if either time is unknown.
+func relativeTime(line results.OutputLine, start time.Time) string {
+	if line.Time.IsZero() || start.IsZero() {
+		return ""
+	}
+
+	return fmt.Sprintf("+%.3fs", line.Time.Sub(start).Seconds())
+}
@@ -2,30 +2,33 @@ package reporter
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/microsoft/storm/internal/artifacts"
 	"github.com/microsoft/storm/internal/devops"
-	"github.com/microsoft/storm/internal/stormerror"
-	"github.com/microsoft/storm/internal/testmgr"
-	"github.com/microsoft/storm/pkg/storm/utils"
+	"github.com/microsoft/storm/pkg/storm/results"
 
 	"github.com/fatih/color"
 )
 
 type TestReporter struct {
 	summary     TestSummary
-	testManager *testmgr.StormTestManager
+	result      results.RunResult
+	azureDevops bool
 	colorize    bool
 }
 
-func NewTestReporter(testManager *testmgr.StormTestManager) *TestReporter {
+func NewTestReporter(result results.RunResult, azureDevops bool) *TestReporter {
 	// Force colors :D
 	color.NoColor = false
 	return &TestReporter{
-		summary:     newSummaryFromTestManager(testManager),
-		testManager: testManager,
+		summary:     NewSummary(result),
+		result:      result,
+		azureDevops: azureDevops,
 		colorize:    true,
 	}
 }
@@ -34,38 +37,90 @@ func (tr *TestReporter) Summary() TestSummary {
 	return tr.summary
 }
 
+// Returns the results of all test cases, preceded by the setup and followed by
+// the cleanup results when present.
+func (tr *TestReporter) allResults() []results.TestCaseResult {
+	all := make([]results.TestCaseResult, 0, len(tr.result.TestCases)+2)
+	if tr.result.Setup != nil {
+		all = append(all, *tr.result.Setup)
+	}
+
+	all = append(all, tr.result.TestCases...)
+
+	if tr.result.Cleanup != nil {
+		all = append(all, *tr.result.Cleanup)
+	}
+
+	return all
+}
+
 func (tr *TestReporter) PrintReport() {
-	tr.printShortReport()
-	tr.printFailureReport()
-	tr.printFinalResult()
+	tr.PrintShortReport()
+	tr.PrintFailureReport()
+	tr.PrintFinalResult()
 }
 
+// SaveLogs saves the output of every test case to a log file named after it in
+// the given directory. The logs of the setup and cleanup are saved in the
+// directories holding their artifacts, so that a test case named the same does
+// not overwrite them.
 func (tr *TestReporter) SaveLogs(dir string) error {
-	for _, testCase := range tr.testManager.TestCases() {
-		filename := fmt.Sprintf("%s.log", testCase.Name())
-		filepath := filepath.Join(dir, filename)
-		err := saveTestCaseLogs(testCase, filepath)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "failed to save logs for %s: %v\n", testCase.Name(), err)
-		}
+	if tr.result.Setup != nil {
+		saveLogsInDir(*tr.result.Setup, filepath.Join(dir, artifacts.SetupDirName))
+	}
+
+	for _, testCase := range tr.result.TestCases {
+		saveLogsInDir(testCase, dir)
+	}
+
+	if tr.result.Cleanup != nil {
+		saveLogsInDir(*tr.result.Cleanup, filepath.Join(dir, artifacts.CleanupDirName))
 	}
 
 	return nil
 }
 
-func saveTestCaseLogs(testCase *testmgr.TestCase, path string) error {
+// saveLogsInDir saves the output of a test case to a log file named after it
+// in the given directory, reporting failures on stderr.
+func saveLogsInDir(testCase results.TestCaseResult, dir string) {
+	err := os.MkdirAll(dir, 0o755)
+	if err == nil {
+		err = saveTestCaseLogs(testCase, filepath.Join(dir, fmt.Sprintf("%s.log", testCase.Name)))
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to save logs for %s: %v\n", testCase.Name, err)
+	}
+}
+
+func saveTestCaseLogs(testCase results.TestCaseResult, path string) error {
 	file, err := os.Create(path)
 	if err != nil {
-		return fmt.Errorf("failed to create log file for %s: %v", testCase.Name(), err)
+		return fmt.Errorf("failed to create log file for %s: %v", testCase.Name, err)
 	}
 	defer file.Close()
 
-	logs := testCase.CollectedOutput()
-	for _, line := range logs {
-		line = strings.TrimSpace(utils.RemoveAllANSI(line)) + "\n"
-		_, err = file.WriteString(line)
+	// The full output was spooled to a file, which already has the format of
+	// saved logs.
+	if testCase.OutputFile != "" {
+		spool, err := os.Open(testCase.OutputFile)
 		if err != nil {
-			return fmt.Errorf("failed to write log line for %s: %v", testCase.Name(), err)
+			return fmt.Errorf("failed to open output of %s: %v", testCase.Name, err)
+		}
+		defer spool.Close()
+
+		_, err = io.Copy(file, spool)
+		if err != nil {
+			return fmt.Errorf("failed to copy output of %s: %v", testCase.Name, err)
+		}
+
+		return nil
+	}
+
+	for _, line := range testCase.Output {
+		_, err = file.WriteString(FormatOutputLine(line) + "\n")
+		if err != nil {
+			return fmt.Errorf("failed to write log line for %s: %v", testCase.Name, err)
 		}
 	}
 
@@ -78,45 +133,45 @@ func (tr *TestReporter) ExitError() error {
 	}
 
 	return fmt.Errorf("%s:%s: %s",
-		tr.testManager.Registrant().RegistrantType().String(),
-		tr.testManager.Registrant().Name(),
+		tr.result.RegistrantType,
+		tr.result.Registrant,
 		tr.summary.Status().String(),
 	)
 }
 
 // Print a simple list of all test cases and their status.
-func (tr *TestReporter) printShortReport() {
+func (tr *TestReporter) PrintShortReport() {
 	printSeparatorWithTitle(fmt.Sprintf(
 		"SUMMARY of %s::%s::%s",
-		tr.testManager.Suite().Name(),
-		tr.testManager.Registrant().RegistrantType().String(),
-		tr.testManager.Registrant().Name(),
+		tr.result.Suite,
+		tr.result.RegistrantType,
+		tr.result.Registrant,
 	))
 
 	ljust := 0
 	// Find the longest test case name
-	for _, testCase := range tr.testManager.TestCases() {
-		if len(testCase.Name()) > ljust {
-			ljust = len(testCase.Name())
+	for _, testCase := range tr.allResults() {
+		if len(testCase.Name) > ljust {
+			ljust = len(testCase.Name)
 		}
 	}
 
-	for _, testCase := range tr.testManager.TestCases() {
-		statusStr := testCase.Status().String()
+	for _, testCase := range tr.allResults() {
+		statusStr := testCase.Status.String()
 		if tr.colorize {
-			statusStr = testCase.Status().ColorString()
+			statusStr = testCase.Status.ColorString()
 		}
 
-		spaces := strings.Repeat(".", max(ljust-len(testCase.Name()), 0))
+		spaces := strings.Repeat(".", max(ljust-len(testCase.Name), 0))
 
 		fmt.Printf(
 			"  %s%s: %s",
-			testCase.Name(),
+			testCase.Name,
 			spaces,
 			statusStr,
 		)
 
-		reason := testCase.Reason()
+		reason := testCase.Reason
 		if reason != "" {
 			if len(reason) > 40 {
 				reason = reason[:40] + "..."
@@ -125,31 +180,107 @@ func (tr *TestReporter) printShortReport() {
 			fmt.Printf(" (%s)", reason)
 		}
 
+		// The setup and cleanup are not test cases, their duration shows how
+		// much of the run they took.
+		if testCase.Index < 0 && testCase.Duration > 0 {
+			fmt.Printf(" [%s]", testCase.Duration.Round(time.Millisecond))
+		}
+
+		if testCase.Rerun {
+			fmt.Print(" [rerun]")
+		}
+
+		// Hook failures do not change the status of the test case.
+		if len(testCase.HookFailures) > 0 {
+			fmt.Printf(" [hooks failed: %d]", len(testCase.HookFailures))
+		}
+
+		// Setting up fixtures may take a large part of a test case.
+		if testCase.FixtureSetup > 0 {
+			fmt.Printf(" [fixtures %s]", testCase.FixtureSetup.Round(time.Millisecond))
+		}
+
 		fmt.Println()
 	}
 
+	tr.printFixtures()
+
 	// Logs devops messages in a separate section
-	if tr.testManager.Suite().AzureDevops() && tr.summary.Status().IsBad() {
+	if tr.azureDevops && tr.summary.Status().IsBad() {
 		printSeparatorWithTitle("DEVOPS LOG")
-		for _, testCase := range tr.testManager.TestCases() {
-			status := testCase.Status()
+		for _, testCase := range tr.allResults() {
+			status := testCase.Status
 			if !status.IsBad() {
 				continue
 			}
 			devops.LogError("%s::%s::%s::%s -> %s (%s)",
-				tr.testManager.Suite().Name(),
-				tr.testManager.Registrant().RegistrantType().String(),
-				tr.testManager.Registrant().Name(),
-				testCase.Name(),
+				tr.result.Suite,
+				tr.result.RegistrantType,
+				tr.result.Registrant,
+				testCase.Name,
 				status.String(),
-				testCase.Reason(),
+				testCase.Reason,
 			)
 		}
+
+		for _, testCase := range tr.allResults() {
+			for _, failure := range testCase.HookFailures {
+				devops.LogError("%s::%s::%s::%s -> %s hook failed (%s)",
+					tr.result.Suite,
+					tr.result.RegistrantType,
+					tr.result.Registrant,
+					testCase.Name,
+					failure.Hook,
+					failure.Error,
+				)
+			}
+		}
+
+		for _, fixture := range tr.result.Fixtures {
+			if fixture.TeardownError != "" {
+				devops.LogError("%s::%s::%s -> fixture '%s' teardown failed (%s)",
+					tr.result.Suite,
+					tr.result.RegistrantType,
+					tr.result.Registrant,
+					fixture.Name,
+					fixture.TeardownError,
+				)
+			}
+		}
+	}
+
+}
+
+// Print how the setup and teardown of every fixture of the run went.
+func (tr *TestReporter) printFixtures() {
+	if len(tr.result.Fixtures) == 0 {
+		return
 	}
 
+	fmt.Println("  Fixtures:")
+	for _, fixture := range tr.result.Fixtures {
+		line := fmt.Sprintf("    %s (%s, for %s): setup %s",
+			fixture.Name,
+			fixture.Scope,
+			fixture.RequestedBy,
+			fixture.SetupDuration.Round(time.Millisecond),
+		)
+
+		if fixture.SetupError != "" {
+			line += fmt.Sprintf(" failed: %s", fixture.SetupError)
+		} else if fixture.TornDown {
+			line += fmt.Sprintf(", teardown %s", fixture.TeardownDuration.Round(time.Millisecond))
+			if fixture.TeardownError != "" {
+				line += fmt.Sprintf(" failed: %s", fixture.TeardownError)
+			}
+		}
+
+		fmt.Println(line)
+	}
 }
 
-func (tr *TestReporter) printFinalResult() {
+// Print the overall status of the run and its summary.
+func (tr *TestReporter) PrintFinalResult() {
 	statusStr := tr.summary.Status().String()
 	if tr.colorize {
 		statusStr = tr.summary.Status().StringColor()
@@ -159,12 +290,13 @@ func (tr *TestReporter) printFinalResult() {
 	fmt.Printf("%s: %s\n", statusStr, tr.summary.Summary())
 }
 
-func (tr *TestReporter) printFailureReport() {
-	isDevops := tr.testManager.Suite().AzureDevops()
+// Print the reason and collected logs of every test case that did not pass.
+func (tr *TestReporter) PrintFailureReport() {
+	isDevops := tr.azureDevops
 	header := true
-	for _, testCase := range tr.testManager.TestCases() {
-		status := testCase.Status()
-		if !isDevops && (status.Passed() || status.NotRun()) {
+	for _, testCase := range tr.allResults() {
+		status := testCase.Status
+		if !isDevops && (status.Passed() || status.NotRun()) && len(testCase.HookFailures) == 0 {
 			continue
 		}
 
@@ -175,18 +307,18 @@ func (tr *TestReporter) printFailureReport() {
 			printSeparatorChar("-")
 		}
 
-		statusStr := testCase.Status().String()
+		statusStr := testCase.Status.String()
 		if tr.colorize {
-			statusStr = testCase.Status().ColorString()
+			statusStr = testCase.Status.ColorString()
 		}
 
 		testCaseHeader := fmt.Sprintf(
 			"Test case: '%s' status: %s; ",
-			testCase.Name(),
+			testCase.Name,
 			statusStr,
 		)
 
-		if reason := testCase.Reason(); reason != "" {
+		if reason := testCase.Reason; reason != "" {
 			testCaseHeader += fmt.Sprintf("reason: %s; ", reason)
 		}
 
@@ -198,12 +330,12 @@ func (tr *TestReporter) printFailureReport() {
 		}
 
 		panicked := false
-		if err, ok := testCase.GetError().(stormerror.PanicError); ok {
+		if testCase.Stack != "" {
 			panicked = true
-			fmt.Printf("Stack trace:\n%s\n", err.Stack)
+			fmt.Printf("Stack trace:\n%s\n", testCase.Stack)
 		}
 
-		logLines := testCase.CollectedOutput()
+		logLines := testCase.Output
 
 		// Check if there are any log lines
 		if len(logLines) == 0 {
@@ -221,7 +353,12 @@ func (tr *TestReporter) printFailureReport() {
 		}
 
 		for _, log := range logLines {
-			lines := simpleWordWrap(log, termWidth()-8)
+			text := log.Text
+			if offset := relativeTime(log, testCase.StartTime); offset != "" {
+				text = fmt.Sprintf("[%s] %s", offset, text)
+			}
+
+			lines := simpleWordWrap(text, termWidth()-8)
 			for i, line := range lines {
 				if i == 0 {
 					fmt.Printf("    ")
@@ -233,8 +370,51 @@ func (tr *TestReporter) printFailureReport() {
 			}
 		}
 
+		for _, failure := range testCase.HookFailures {
+			fmt.Printf("%s hook failed: %s\n", failure.Hook, failure.Error)
+			if failure.Stack != "" {
+				fmt.Printf("Stack trace:\n%s\n", failure.Stack)
+			}
+		}
+
+		if len(testCase.Artifacts) > 0 {
+			fmt.Println("Published artifacts:")
+			for _, artifact := range testCase.Artifacts {
+				fmt.Printf("    %s\n", tr.artifactLocation(artifact))
+			}
+		}
+
 		if grp != nil {
 			grp.Close()
 		}
 	}
 }
+
+// Returns where an artifact can be found, with its description, size limit
+// notes and upload URL if any.
+func (tr *TestReporter) artifactLocation(artifact results.Artifact) string {
+	location := artifact.Name
+	if artifact.Location() != "" {
+		location = filepath.Join(tr.result.LogDir, filepath.FromSlash(artifact.Location()))
+	}
+
+	if artifact.Bundle != "" {
+		location += fmt.Sprintf(" [%s]", artifact.Path)
+	}
+
+	if artifact.Description != "" {
+		location += fmt.Sprintf(" (%s)", artifact.Description)
+	}
+
+	if artifact.Reason != "" {
+		location += fmt.Sprintf(": %s", artifact.Reason)
+	}
+
+	if artifact.URL != "" {
+		location += fmt.Sprintf("\n        uploaded to %s", artifact.URL)
+	} else if artifact.UploadError != "" {
+		location += fmt.Sprintf("\n        upload failed: %s", artifact.UploadError)
+	}
+
+	return location
+}
@@ -0,0 +1,47 @@
+package reporter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/microsoft/storm/pkg/storm/results"
+)
+
+func TestSaveLogsKeepsStepsApart(t *testing.T) {
+	output := func(text string) []results.OutputLine {
+		return []results.OutputLine{{Stream: results.OutputStreamStdout, Text: text}}
+	}
+
+	run := results.RunResult{
+		TestCases: []results.TestCaseResult{
+			{TestCaseInfo: results.TestCaseInfo{Name: "setup"}, Status: results.TestCaseStatusPassed, Output: output("from the test case")},
+		},
+		Setup:   &results.TestCaseResult{TestCaseInfo: results.TestCaseInfo{Name: "setup", Index: -1}, Status: results.TestCaseStatusPassed, Output: output("from the setup")},
+		Cleanup: &results.TestCaseResult{TestCaseInfo: results.TestCaseInfo{Name: "cleanup", Index: -1}, Status: results.TestCaseStatusPassed, Output: output("from the cleanup")},
+	}
+
+	dir := t.TempDir()
+	if err := NewTestReporter(run, false).SaveLogs(dir); err != nil {
+		t.Fatalf("failed to save logs: %v", err)
+	}
+
+	expected := map[string]string{
+		"setup.log":            "from the test case",
+		"_setup/setup.log":     "from the setup",
+		"_cleanup/cleanup.log": "from the cleanup",
+	}
+
+	for name, text := range expected {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Errorf("failed to read %s: %v", name, err)
+			continue
+		}
+
+		if !strings.Contains(string(data), text) {
+			t.Errorf("expected %s to contain '%s', got '%s'", name, text, data)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package reporter
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/microsoft/storm/internal/stormerror"
+	"github.com/microsoft/storm/internal/testmgr"
+	"github.com/microsoft/storm/pkg/storm/results"
+)
+
+// Registrants that expose the scenario metadata, such as the runner's
+// runnable instances.
+type scenarioMetadata interface {
+	Tags() []string
+	StagePaths() []string
+}
+
+// NewRunInfo describes the run managed by the given test manager.
+func NewRunInfo(tm *testmgr.StormTestManager) results.RunInfo {
+	names := make([]string, len(tm.TestCases()))
+	for i, testCase := range tm.TestCases() {
+		names[i] = testCase.Name()
+	}
+
+	info := results.RunInfo{
+		Suite:          tm.Suite().Name(),
+		RegistrantType: tm.Registrant().RegistrantType().String(),
+		Registrant:     tm.Registrant().Name(),
+		Args:           tm.Args(),
+		StartTime:      tm.StartTime(),
+		TestCases:      names,
+	}
+
+	if metadata, ok := tm.Registrant().(scenarioMetadata); ok {
+		info.Tags = metadata.Tags()
+		info.StagePaths = metadata.StagePaths()
+	}
+
+	hostname, err := os.Hostname()
+	if err == nil {
+		info.Hostname = hostname
+	}
+
+	if logDir := tm.LogDir(); logDir != nil {
+		info.LogDir, err = filepath.Abs(*logDir)
+		if err != nil {
+			info.LogDir = *logDir
+		}
+	}
+
+	return info
+}
+
+// NewTestCaseInfo describes the given test case, located at position index of
+// the run.
+func NewTestCaseInfo(testCase *testmgr.TestCase, index int) results.TestCaseInfo {
+	return results.TestCaseInfo{
+		Name:  testCase.Name(),
+		Index: index,
+	}
+}
+
+// NewTestCaseResult produces the result of the given test case, located at
+// position index of the run.
+func NewTestCaseResult(testCase *testmgr.TestCase, index int) results.TestCaseResult {
+	result := results.TestCaseResult{
+		TestCaseInfo:  NewTestCaseInfo(testCase, index),
+		Status:        testCase.Status(),
+		Reason:        testCase.Reason(),
+		Output:        testCase.CollectedOutput(),
+		OutputOmitted: testCase.OutputOmitted(),
+		OutputFile:    testCase.OutputFile(),
+		Artifacts:     testCase.Artifacts(),
+		HookFailures:  testCase.HookFailures(),
+
+		PreviouslyPassed: testCase.PreviouslyPassed() && testCase.Status() == results.TestCaseStatusPassed,
+		Rerun:            testCase.IsRerun(),
+	}
+
+	if err, ok := testCase.GetError().(stormerror.PanicError); ok {
+		result.Stack = string(err.Stack)
+	}
+
+	// Only test cases that actually started have meaningful timings.
+	if !testCase.StartTime().IsZero() {
+		result.StartTime = testCase.StartTime()
+		result.Duration = testCase.RunTime()
+		result.FixtureSetup = testCase.FixtureSetupTime()
+	}
+
+	return result
+}
+
+// NewRunResult produces the result of the run managed by the given test
+// manager.
+func NewRunResult(tm *testmgr.StormTestManager) results.RunResult {
+	testCases := make([]results.TestCaseResult, len(tm.TestCases()))
+	for i, testCase := range tm.TestCases() {
+		testCases[i] = NewTestCaseResult(testCase, i)
+	}
+
+	return results.RunResult{
+		RunInfo:   NewRunInfo(tm),
+		Duration:  tm.Duration(),
+		TestCases: testCases,
+		Artifacts: tm.Artifacts(),
+		Fixtures:  tm.FixtureResults(),
+		State:     tm.StateResults(),
+	}
+}
@@ -4,7 +4,7 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/microsoft/storm/internal/testmgr"
+	"github.com/microsoft/storm/pkg/storm/results"
 )
 
 type TestSummary struct {
@@ -14,26 +14,65 @@ type TestSummary struct {
 	skipped int
 	notRun  int
 	errored int
+
+	// Test cases that passed in the run this one resumed, counted as passed.
+	previouslyPassed int
+
+	// Setup, cleanup, fixture teardown and hook failures are counted
+	// separately from test cases.
+	setupErrored    int
+	cleanupErrored  int
+	teardownErrored int
+	hooksErrored    int
 }
 
-func newSummaryFromTestManager(tm *testmgr.StormTestManager) TestSummary {
+// NewSummary produces a summary aggregating the results of all given runs.
+func NewSummary(runs ...results.RunResult) TestSummary {
 	var summary TestSummary
 
-	for _, testCase := range tm.TestCases() {
-		summary.total++
-		switch testCase.Status() {
-		case testmgr.TestCaseStatusPassed:
-			summary.passed++
-		case testmgr.TestCaseStatusFailed:
-			summary.failed++
-		case testmgr.TestCaseStatusSkipped:
-			summary.skipped++
-		case testmgr.TestCaseStatusNotRun:
-			summary.notRun++
-		case testmgr.TestCaseStatusError:
-			summary.errored++
-		default:
-			panic("Invalid test case status")
+	for _, run := range runs {
+		for _, testCase := range run.TestCases {
+			summary.total++
+			switch testCase.Status {
+			case results.TestCaseStatusPassed:
+				summary.passed++
+				if testCase.PreviouslyPassed {
+					summary.previouslyPassed++
+				}
+			case results.TestCaseStatusFailed:
+				summary.failed++
+			case results.TestCaseStatusSkipped:
+				summary.skipped++
+			case results.TestCaseStatusNotRun:
+				summary.notRun++
+			case results.TestCaseStatusError:
+				summary.errored++
+			// Saved results of a run that was killed can contain test cases
+			// that never finished: the one that was running errored, the
+			// pending ones did not run.
+			case results.TestCaseStatusRunning:
+				summary.errored++
+			case results.TestCaseStatusPending:
+				summary.notRun++
+			default:
+				panic("Invalid test case status")
+			}
+
+			summary.hooksErrored += len(testCase.HookFailures)
+		}
+
+		if run.Setup != nil && run.Setup.Status.IsBad() {
+			summary.setupErrored++
+		}
+
+		if run.Cleanup != nil && run.Cleanup.Status.IsBad() {
+			summary.cleanupErrored++
+		}
+
+		for _, fixture := range run.Fixtures {
+			if fixture.TeardownError != "" {
+				summary.teardownErrored++
+			}
 		}
 	}
 
@@ -41,7 +80,7 @@ func newSummaryFromTestManager(tm *testmgr.StormTestManager) TestSummary {
 }
 
 func (s TestSummary) Status() TestSummaryStatus {
-	if s.errored > 0 {
+	if s.errored > 0 || s.setupErrored > 0 || s.cleanupErrored > 0 || s.teardownErrored > 0 || s.hooksErrored > 0 {
 		return TestStatusError
 	}
 	if s.failed > 0 {
@@ -53,6 +92,22 @@ func (s TestSummary) Status() TestSummaryStatus {
 func (s TestSummary) Summary() string {
 	var out []string
 
+	if s.setupErrored > 0 {
+		out = append(out, fmt.Sprintf("setup errored: %d", s.setupErrored))
+	}
+
+	if s.cleanupErrored > 0 {
+		out = append(out, fmt.Sprintf("cleanup errored: %d", s.cleanupErrored))
+	}
+
+	if s.teardownErrored > 0 {
+		out = append(out, fmt.Sprintf("fixture teardown errored: %d", s.teardownErrored))
+	}
+
+	if s.hooksErrored > 0 {
+		out = append(out, fmt.Sprintf("hooks errored: %d", s.hooksErrored))
+	}
+
 	if s.failed > 0 {
 		out = append(out, fmt.Sprintf("failed: %d", s.failed))
 	}
@@ -67,7 +122,11 @@ func (s TestSummary) Summary() string {
 		out = append(out, fmt.Sprintf("notrun: %d", s.notRun))
 	}
 
-	out = append(out, fmt.Sprintf("passed: %d", s.passed))
+	if s.previouslyPassed > 0 {
+		out = append(out, fmt.Sprintf("passed: %d (%d previously)", s.passed, s.previouslyPassed))
+	} else {
+		out = append(out, fmt.Sprintf("passed: %d", s.passed))
+	}
 	out = append(out, fmt.Sprintf("total: %d", s.total))
 
 	return strings.Join(out, "; ")
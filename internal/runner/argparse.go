@@ -43,13 +43,7 @@ func parseExtraArguments(
 		)
 	}
 
-	// If the first argument is '--', we skip it
-	var startArg = 0
-	if len(argList) != 0 && argList[0] == "--" {
-		startArg = 1
-	}
-
-	actualArgs := argList[startArg:]
+	actualArgs := stripPassthroughSeparator(argList)
 
 	suite.Logger().Debugf(
 		"Parsing extra arguments for %s '%s': %v",
@@ -70,3 +64,14 @@ func parseExtraArguments(
 
 	return nil
 }
+
+// stripPassthroughSeparator removes the leading '--' used to force passthrough
+// of the extra arguments, if present.
+func stripPassthroughSeparator(argList []string) []string {
+	// If the first argument is '--', we skip it
+	if len(argList) != 0 && argList[0] == "--" {
+		return argList[1:]
+	}
+
+	return argList
+}
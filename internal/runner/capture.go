@@ -0,0 +1,283 @@
+package runner
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/microsoft/storm/internal/reporter"
+	"github.com/microsoft/storm/internal/slogcapture"
+	"github.com/microsoft/storm/internal/testmgr"
+	"github.com/microsoft/storm/pkg/storm/results"
+
+	"github.com/sirupsen/logrus"
+)
+
+// captureOptions controls how the output of a test case is captured.
+type captureOptions struct {
+	// Directory where the full output of each test case is spooled to.
+	spoolDir string
+
+	// Number of lines kept in memory from the start of the output.
+	headLines int
+
+	// Number of lines kept in memory from the end of the output.
+	tailLines int
+
+	// Called for each line of output, which can be used to forward the output
+	// to another writer (e.g. the console). It is called synchronously, so it
+	// should not block for too long.
+	forward func(io.Writer, results.OutputLine)
+}
+
+// capturedOutput is the output captured while running a function.
+type capturedOutput struct {
+	// Lines kept in memory. When lines were omitted, a marker line is placed
+	// between the head and the tail.
+	lines []results.OutputLine
+
+	// Number of lines that were not kept in memory.
+	omitted int
+
+	// Path of the file holding the full output.
+	file string
+}
+
+// captureOutput runs the given function f while capturing all output to
+// stdout, stderr and the standard logrus logger, at every level. Every line is
+// tagged with the time it was captured at and the source it came from, and
+// written to a spool file in opts.spoolDir. Only the first and last lines are
+// kept in memory, as configured in opts. Lines longer than
+// results.MaxOutputLineLength are split. The function f receives a sink that
+// records lines directly, without going through stdout or stderr. The function
+// returns an error if it fails to capture the output.
+func captureOutput(opts captureOptions, f func(sink func(results.OutputLine))) (*capturedOutput, error) {
+	spool, err := os.CreateTemp(opts.spoolDir, "*.log")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output spool file: %w", err)
+	}
+	defer spool.Close()
+
+	oldStdout := os.Stdout
+	oldStderr := os.Stderr
+
+	rOut, wOut, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdout capture pipe: %w", err)
+	}
+
+	rErr, wErr, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stderr capture pipe: %w", err)
+	}
+
+	buffer := newOutputBuffer(bufio.NewWriter(spool), opts.headLines, opts.tailLines)
+	var outMutex sync.Mutex
+	var wg sync.WaitGroup
+
+	// Lines read from the pipes are timestamped when they are read, while
+	// holding the lock so that their order always matches the order of the
+	// timestamps. Logrus lines keep the time of their entry.
+	var record = func(line results.OutputLine, w io.Writer) {
+		for _, text := range splitLongLine(line.Text) {
+			line.Text = text
+			outMutex.Lock()
+			if line.Time.IsZero() {
+				line.Time = time.Now()
+			}
+			buffer.add(line)
+			outMutex.Unlock()
+			opts.forward(w, line)
+		}
+	}
+
+	os.Stdout = wOut
+	os.Stderr = wErr
+
+	// Entries of the standard logrus logger are recorded by a hook rather than
+	// through the stderr pipe, so that they keep their level and the time they
+	// were logged at. While capturing, the logger writes nowhere else, so that
+	// only the forwarding decides what reaches the console, and it is set to
+	// TRACE level so that every entry is kept in the saved logs. Its formatter
+	// is left alone.
+	logrusOutput := logrus.StandardLogger().Out
+	logrusLevel := logrus.StandardLogger().GetLevel()
+	logrusHooks := logrus.StandardLogger().ReplaceHooks(withHook(
+		logrus.StandardLogger().Hooks,
+		testmgr.NewLogrusCaptureHook(&logrus.TextFormatter{ForceColors: true}, func(line results.OutputLine) {
+			record(line, oldStderr)
+		}),
+	))
+	logrus.SetOutput(io.Discard)
+	logrus.SetLevel(logrus.TraceLevel)
+
+	defer func() {
+		os.Stdout = oldStdout
+		os.Stderr = oldStderr
+
+		// Restore the original logrus configuration
+		logrus.StandardLogger().ReplaceHooks(logrusHooks)
+		logrus.SetOutput(logrusOutput)
+		logrus.SetLevel(logrusLevel)
+	}()
+
+	// Lines are read with a buffer of the maximum line length, so that longer
+	// lines are split rather than buffered whole.
+	var streamReader = func(r io.Reader, w io.Writer, stream results.OutputStream) {
+		defer wg.Done()
+		reader := bufio.NewReaderSize(r, results.MaxOutputLineLength)
+		for {
+			data, err := reader.ReadSlice('\n')
+			if len(data) > 0 {
+				record(results.OutputLine{
+					Stream: stream,
+					Text:   strings.TrimSuffix(string(data), "\n"),
+				}, w)
+			}
+
+			if err != nil && !errors.Is(err, bufio.ErrBufferFull) {
+				return
+			}
+		}
+	}
+
+	wg.Add(2)
+
+	go streamReader(rOut, oldStdout, results.OutputStreamStdout)
+	go streamReader(rErr, oldStderr, results.OutputStreamStderr)
+
+	var sink = func(line results.OutputLine) {
+		w := oldStdout
+		if line.Stream != results.OutputStreamStdout {
+			w = oldStderr
+		}
+
+		record(line, w)
+	}
+
+	// Records of slog loggers following the active capture, such as the
+	// suite's, are recorded through the sink as well.
+	restoreSink := slogcapture.SetActiveSink(sink)
+	f(sink)
+	restoreSink()
+
+	wOut.Close()
+	wErr.Close()
+
+	wg.Wait()
+
+	err = buffer.spool.Flush()
+	if err != nil {
+		return nil, fmt.Errorf("failed to write output spool file: %w", err)
+	}
+
+	return &capturedOutput{
+		lines:   buffer.lines(),
+		omitted: buffer.omitted,
+		file:    spool.Name(),
+	}, nil
+}
+
+// splitLongLine splits a line into chunks of at most
+// results.MaxOutputLineLength bytes.
+func splitLongLine(text string) []string {
+	if len(text) <= results.MaxOutputLineLength {
+		return []string{text}
+	}
+
+	chunks := make([]string, 0, len(text)/results.MaxOutputLineLength+1)
+	for len(text) > results.MaxOutputLineLength {
+		chunks = append(chunks, text[:results.MaxOutputLineLength])
+		text = text[results.MaxOutputLineLength:]
+	}
+
+	return append(chunks, text)
+}
+
+// outputBuffer writes every line it receives to a spool, and keeps the first
+// and last lines in memory.
+type outputBuffer struct {
+	spool     *bufio.Writer
+	headLimit int
+	tailLimit int
+	head      []results.OutputLine
+	tail      []results.OutputLine
+	tailStart int
+	omitted   int
+}
+
+func newOutputBuffer(spool *bufio.Writer, headLimit, tailLimit int) *outputBuffer {
+	return &outputBuffer{
+		spool:     spool,
+		headLimit: max(headLimit, 0),
+		tailLimit: max(tailLimit, 0),
+	}
+}
+
+func (b *outputBuffer) add(line results.OutputLine) {
+	// Write errors are sticky in bufio.Writer and reported when flushing.
+	b.spool.WriteString(reporter.FormatOutputLine(line) + "\n")
+
+	if len(b.head) < b.headLimit {
+		b.head = append(b.head, line)
+		return
+	}
+
+	if b.tailLimit == 0 {
+		b.omitted++
+		return
+	}
+
+	// The tail is a ring buffer, tailStart is the position of its oldest line.
+	if len(b.tail) < b.tailLimit {
+		b.tail = append(b.tail, line)
+		return
+	}
+
+	b.tail[b.tailStart] = line
+	b.tailStart = (b.tailStart + 1) % b.tailLimit
+	b.omitted++
+}
+
+// lines returns the lines kept in memory in order, with a marker line between
+// the head and the tail if any lines were omitted.
+func (b *outputBuffer) lines() []results.OutputLine {
+	lines := make([]results.OutputLine, 0, len(b.head)+len(b.tail)+1)
+	lines = append(lines, b.head...)
+
+	tail := append(b.tail[b.tailStart:len(b.tail):len(b.tail)], b.tail[:b.tailStart]...)
+	if b.omitted > 0 {
+		// The marker takes the time of the first line after the gap, so that
+		// it stays in place when lines are sorted by time.
+		marker := results.OutputLine{
+			Stream: results.OutputStreamStorm,
+			Text:   fmt.Sprintf("... %d lines omitted, see the saved logs for the full output ...", b.omitted),
+		}
+
+		if len(tail) > 0 {
+			marker.Time = tail[0].Time
+		} else if len(b.head) > 0 {
+			marker.Time = b.head[len(b.head)-1].Time
+		}
+
+		lines = append(lines, marker)
+	}
+
+	return append(lines, tail...)
+}
+
+// withHook returns a copy of the given hooks with the given hook added.
+func withHook(hooks logrus.LevelHooks, hook logrus.Hook) logrus.LevelHooks {
+	newHooks := make(logrus.LevelHooks, len(hooks))
+	for level, levelHooks := range hooks {
+		newHooks[level] = append([]logrus.Hook(nil), levelHooks...)
+	}
+
+	newHooks.Add(hook)
+	return newHooks
+}
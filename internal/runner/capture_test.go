@@ -0,0 +1,107 @@
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/microsoft/storm/pkg/storm/results"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestOutputBuffer(t *testing.T) {
+	var spool bytes.Buffer
+	writer := bufio.NewWriter(&spool)
+	buffer := newOutputBuffer(writer, 2, 3)
+	for i := 0; i < 10; i++ {
+		buffer.add(results.OutputLine{Stream: results.OutputStreamStdout, Text: fmt.Sprintf("line %d", i)})
+	}
+	writer.Flush()
+
+	if buffer.omitted != 5 {
+		t.Errorf("expected 5 omitted lines, got %d", buffer.omitted)
+	}
+
+	var texts []string
+	for _, line := range buffer.lines() {
+		texts = append(texts, line.Text)
+	}
+
+	expected := []string{"line 0", "line 1", "... 5 lines omitted, see the saved logs for the full output ...", "line 7", "line 8", "line 9"}
+	if strings.Join(texts, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected lines %q, got %q", expected, texts)
+	}
+
+	if lines := strings.Count(spool.String(), "\n"); lines != 10 {
+		t.Errorf("expected 10 spooled lines, got %d", lines)
+	}
+}
+
+func TestSplitLongLine(t *testing.T) {
+	text := strings.Repeat("x", results.MaxOutputLineLength*2+1)
+	chunks := splitLongLine(text)
+	if len(chunks) != 3 || strings.Join(chunks, "") != text {
+		t.Errorf("expected 3 chunks making up the line, got %d", len(chunks))
+	}
+}
+
+func TestCaptureOutputRedirectsStandardLogger(t *testing.T) {
+	stderr, err := os.CreateTemp(t.TempDir(), "stderr")
+	if err != nil {
+		t.Fatalf("failed to create stderr file: %v", err)
+	}
+	defer stderr.Close()
+
+	oldStderr := os.Stderr
+	os.Stderr = stderr
+	defer func() { os.Stderr = oldStderr }()
+
+	oldOutput := logrus.StandardLogger().Out
+	oldLevel := logrus.GetLevel()
+	logrus.SetOutput(stderr)
+	logrus.SetLevel(logrus.InfoLevel)
+	defer func() {
+		logrus.SetOutput(oldOutput)
+		logrus.SetLevel(oldLevel)
+	}()
+
+	// Not in watch mode: nothing is forwarded to the console.
+	opts := captureOptions{
+		spoolDir:  t.TempDir(),
+		headLines: 10,
+		forward:   func(io.Writer, results.OutputLine) {},
+	}
+
+	captured, err := captureOutput(opts, func(func(results.OutputLine)) {
+		logrus.Info("informative")
+		logrus.Debug("detailed")
+	})
+	if err != nil {
+		t.Fatalf("failed to capture output: %v", err)
+	}
+
+	var levels []string
+	for _, line := range captured.lines {
+		if line.Stream == results.OutputStreamLogrus {
+			levels = append(levels, line.Level)
+		}
+	}
+
+	if strings.Join(levels, ",") != "info,debug" {
+		t.Errorf("expected info and debug entries to be captured, got %q", levels)
+	}
+
+	data, _ := os.ReadFile(stderr.Name())
+	if len(data) > 0 {
+		t.Errorf("expected nothing written to stderr, got %q", data)
+	}
+
+	if logrus.StandardLogger().Out != stderr || logrus.GetLevel() != logrus.InfoLevel {
+		t.Errorf("expected the standard logger configuration to be restored")
+	}
+}
@@ -0,0 +1,99 @@
+package runner
+
+import (
+	"path/filepath"
+
+	"github.com/microsoft/storm/internal/checkpoint"
+	"github.com/microsoft/storm/internal/testmgr"
+	"github.com/microsoft/storm/pkg/storm/core"
+	"github.com/microsoft/storm/pkg/storm/results"
+)
+
+// CheckpointOptions controls the checkpoint saved after each test case and
+// the checkpoint a run resumes from.
+type CheckpointOptions struct {
+	// Path to save the checkpoint to. Defaults to checkpoint.FileName in the
+	// log directory, if any.
+	Path *string
+
+	// Path of a checkpoint to resume, if not nil.
+	Resume *string
+}
+
+// path returns where to save the checkpoint of a run saving its logs to
+// logDir, or false if it is not saved.
+func (o CheckpointOptions) path(logDir *string) (string, bool) {
+	switch {
+	case o.Path != nil:
+		return *o.Path, true
+	case logDir != nil:
+		return filepath.Join(*logDir, checkpoint.FileName), true
+	default:
+		return "", false
+	}
+}
+
+// resume loads the checkpoint to resume, if any, and skips the test cases
+// that passed in it.
+func (o CheckpointOptions) resume(suite core.SuiteContext, testManager *testmgr.StormTestManager) error {
+	if o.Resume == nil {
+		return nil
+	}
+
+	saved, err := checkpoint.Load(*o.Resume)
+	if err != nil {
+		return err
+	}
+
+	passed, err := testManager.Resume(saved)
+	if err != nil {
+		return err
+	}
+
+	testCases := testManager.TestCases()
+	switch passed {
+	case 0:
+		suite.Logger().Warnf("Resuming from '%s', where no test case passed: running all test cases", *o.Resume)
+	case len(testCases):
+		suite.Logger().Warnf("Resuming from '%s', where all test cases passed: running none of them", *o.Resume)
+	default:
+		suite.Logger().Infof("Resuming from '%s': skipping %d test cases that previously passed, restarting at '%s'", *o.Resume, passed, testCases[passed].Name())
+	}
+
+	return nil
+}
+
+// checkpointReporter saves the checkpoint of the run after each test case and
+// once the run has finished. Failures to save it are logged, they do not
+// affect the run.
+type checkpointReporter struct {
+	core.BaseReporter
+	suite       core.SuiteContext
+	path        string
+	testManager *testmgr.StormTestManager
+}
+
+func newCheckpointReporter(suite core.SuiteContext, path string, testManager *testmgr.StormTestManager) *checkpointReporter {
+	suite.Logger().Infof("Saving checkpoints to '%s'", path)
+	return &checkpointReporter{
+		suite:       suite,
+		path:        path,
+		testManager: testManager,
+	}
+}
+
+func (r *checkpointReporter) TestCaseFinished(results.TestCaseResult) {
+	r.save()
+}
+
+func (r *checkpointReporter) RunFinished(results.RunResult) error {
+	r.save()
+	return nil
+}
+
+func (r *checkpointReporter) save() {
+	err := checkpoint.Save(r.path, r.testManager.Checkpoint())
+	if err != nil {
+		r.suite.Logger().Warn(err)
+	}
+}
@@ -1,10 +1,42 @@
 package runner
 
 import (
+	"context"
+	"log/slog"
+
+	"github.com/microsoft/storm/pkg/storm/artifacts"
 	"github.com/microsoft/storm/pkg/storm/core"
+
+	"github.com/sirupsen/logrus"
 )
 
 type runnableContext struct {
 	core.TestRegistrantMetadata
 	core.LoggerProvider
+
+	ctx    context.Context
+	broker artifacts.ArtifactBroker
+}
+
+func (c *runnableContext) Context() context.Context {
+	return c.ctx
+}
+
+func (c *runnableContext) ArtifactBroker() artifacts.ArtifactBroker {
+	return c.broker
+}
+
+// stepLoggers provides the loggers of a setup or cleanup step, which record
+// into the step's captured output.
+type stepLoggers struct {
+	logger     *logrus.Logger
+	slogLogger *slog.Logger
+}
+
+func (l stepLoggers) Logger() *logrus.Logger {
+	return l.logger
+}
+
+func (l stepLoggers) SlogLogger() *slog.Logger {
+	return l.slogLogger
 }
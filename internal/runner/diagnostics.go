@@ -0,0 +1,82 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/microsoft/storm/internal/testmgr"
+	"github.com/microsoft/storm/pkg/storm/core"
+)
+
+// diagnostics holds the diagnostics collectors run after each test case of a
+// run that failed or errored, and the time they may take in total for a test
+// case.
+type diagnostics struct {
+	collectors []core.NamedDiagnosticsCollector
+	budget     time.Duration
+}
+
+// newDiagnostics collects the diagnostics collectors of the suite. A budget of
+// zero disables them.
+func newDiagnostics(suite core.SuiteContext, budget time.Duration) diagnostics {
+	return diagnostics{
+		collectors: suite.DiagnosticsCollectors(),
+		budget:     budget,
+	}
+}
+
+// collect runs the diagnostics collectors for the given closed test case if it
+// failed or errored. They are given up on once the budget runs out, or as
+// described by guard. Their failures are only logged, so that they never
+// change the status of the test case.
+func (d diagnostics) collect(testCase *testmgr.TestCase, guard interruptGuard) {
+	if len(d.collectors) == 0 || d.budget <= 0 || !testCase.Status().IsBad() {
+		return
+	}
+
+	log := testCase.Logger()
+	if cause := guard.interruption(); cause != nil {
+		log.Warnf("Not collecting diagnostics: %v", cause)
+		return
+	}
+
+	// The context of the test case was cancelled when it was closed.
+	ctx, cancel := testCase.RenewContext(d.budget)
+	defer cancel()
+
+	guard = guard.withDeadline(time.Now().Add(d.budget))
+	guard.ctx = ctx
+
+	for _, collector := range d.collectors {
+		if ctx.Err() != nil {
+			log.Warnf("Not running diagnostics collector '%s': %v", collector.Name, d.interruption(guard))
+			continue
+		}
+
+		log.Infof("Collecting diagnostics with '%s'", collector.Name)
+		broker := testCase.DiagnosticsBroker(collector.Name)
+		finished, err := guard.run(func() error {
+			return collector.Collect(testCase, broker)
+		})
+		if !finished {
+			err = fmt.Errorf("%w, gave up waiting for it", d.interruption(guard))
+		}
+
+		if err != nil {
+			log.Warnf("Diagnostics collector '%s' failed: %v", collector.Name, err)
+		}
+	}
+}
+
+// interruption returns why the collectors were stopped, describing when the
+// budget ran out.
+func (d diagnostics) interruption(guard interruptGuard) error {
+	cause := guard.interruption()
+	if errors.Is(cause, context.DeadlineExceeded) {
+		return fmt.Errorf("the diagnostics budget of %s ran out", d.budget)
+	}
+
+	return cause
+}
@@ -0,0 +1,102 @@
+package runner
+
+import (
+	"fmt"
+
+	"github.com/microsoft/storm/internal/testmgr"
+	"github.com/microsoft/storm/pkg/storm/core"
+)
+
+// testCaseHooks holds the hooks run before and after each test case of a
+// run, in order.
+type testCaseHooks struct {
+	before []core.NamedTestCaseHook
+	after  []core.NamedTestCaseHook
+}
+
+// newTestCaseHooks collects the hooks of the suite and of the runnable. The
+// suite's BeforeEach hooks run first and its AfterEach hooks last, so that
+// they wrap those of the runnable.
+func newTestCaseHooks(suite core.SuiteContext, runnable *runnableInstance) testCaseHooks {
+	hooks := testCaseHooks{
+		before: suiteHooks("BeforeEach", suite.BeforeEachHooks()),
+	}
+
+	if r, ok := runnable.TestRegistrant.(core.BeforeEachHook); ok {
+		hooks.before = append(hooks.before, core.NamedTestCaseHook{Name: "BeforeEach", Hook: r.BeforeEach})
+	}
+
+	if r, ok := runnable.TestRegistrant.(core.AfterEachHook); ok {
+		hooks.after = append(hooks.after, core.NamedTestCaseHook{Name: "AfterEach", Hook: r.AfterEach})
+	}
+
+	hooks.after = append(hooks.after, suiteHooks("AfterEach", suite.AfterEachHooks())...)
+	return hooks
+}
+
+// suiteHooks names the given hooks registered in the suite after their kind.
+func suiteHooks(kind string, registered []core.NamedTestCaseHook) []core.NamedTestCaseHook {
+	hooks := make([]core.NamedTestCaseHook, len(registered))
+	for i, hook := range registered {
+		hooks[i] = core.NamedTestCaseHook{
+			Name: fmt.Sprintf("suite %s '%s'", kind, hook.Name),
+			Hook: hook.Hook,
+		}
+	}
+
+	return hooks
+}
+
+// runBefore runs the BeforeEach hooks for the given started test case, and
+// reports whether the test case may run. The first failing hook stops the
+// others and closes the test case as not run; a hook may also close the test
+// case itself.
+func (h testCaseHooks) runBefore(testCase *testmgr.TestCase, guard interruptGuard) bool {
+	for _, hook := range h.before {
+		err := runHook(testCase, guard, hook)
+		if !testCase.Status().IsRunning() {
+			return false
+		}
+
+		if err != nil {
+			testCase.MarkNotRun(fmt.Sprintf("%s hook failed: %v", hook.Name, err))
+			return false
+		}
+	}
+
+	return true
+}
+
+// runAfter runs the AfterEach hooks for the given closed test case. Their
+// failures do not change its status.
+func (h testCaseHooks) runAfter(testCase *testmgr.TestCase, guard interruptGuard) {
+	if len(h.after) == 0 {
+		return
+	}
+
+	// The context of the test case was cancelled when it was closed.
+	_, cancel := testCase.RenewContext(0)
+	defer cancel()
+
+	for _, hook := range h.after {
+		runHook(testCase, guard, hook)
+	}
+}
+
+// runHook runs a hook for the given test case as described by guard, and
+// records its failure in the test case.
+func runHook(testCase *testmgr.TestCase, guard interruptGuard, hook core.NamedTestCaseHook) error {
+	finished, err := guard.run(func() error {
+		return hook.Hook(testCase)
+	})
+	if !finished {
+		err = fmt.Errorf("%w, gave up waiting for the hook", guard.interruption())
+	}
+
+	if err != nil {
+		testCase.Logger().Errorf("%s hook failed: %v", hook.Name, err)
+		testCase.RecordHookFailure(hook.Name, err)
+	}
+
+	return err
+}
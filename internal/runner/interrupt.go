@@ -0,0 +1,132 @@
+package runner
+
+import (
+	"context"
+	"time"
+)
+
+// interruptGuard waits for the test cases, setup, cleanup and suite cleanup
+// functions of a run. Once the run is interrupted, it only waits for them for
+// a grace period, and never past the deadline if one is set, so that the
+// reports are written even when they do not stop.
+type interruptGuard struct {
+	ctx      context.Context
+	grace    time.Duration
+	deadline time.Time
+
+	// Whether the guard waits for cleanups, which are given a context that
+	// outlives the interruption of the run.
+	cleanup bool
+}
+
+// forCleanups returns a copy of the guard waiting for cleanups, see
+// runWithContext.
+func (g interruptGuard) forCleanups() interruptGuard {
+	g.cleanup = true
+	return g
+}
+
+// interruption returns why the run was interrupted, or nil if it was not.
+func (g interruptGuard) interruption() error {
+	if g.ctx.Err() == nil {
+		return nil
+	}
+
+	return context.Cause(g.ctx)
+}
+
+// limit returns how long to wait for a function once the run is interrupted,
+// and false to wait for it indefinitely.
+func (g interruptGuard) limit() (time.Duration, bool) {
+	if g.deadline.IsZero() {
+		return g.grace, g.grace > 0
+	}
+
+	limit := max(time.Until(g.deadline), 0)
+	if g.grace > 0 {
+		limit = min(limit, g.grace)
+	}
+
+	return limit, true
+}
+
+// run runs f in its own goroutine, catching panics, and waits for it to
+// return. Once the run is interrupted, it gives up on f after the grace
+// period, counted from the interruption or from the call if it was already
+// interrupted, or at the deadline. It reports whether f returned, along with
+// its error. A grace period of zero without a deadline waits for f
+// indefinitely.
+func (g interruptGuard) run(f func() error) (finished bool, err error) {
+	done := make(chan error, 1)
+	go func() {
+		// runtime.Goexit() skips the send, close the channel so that the
+		// function still counts as finished.
+		defer close(done)
+		done <- runCatchPanic(f)
+	}()
+
+	select {
+	case err := <-done:
+		return true, err
+	case <-g.ctx.Done():
+	}
+
+	limit, limited := g.limit()
+	if !limited {
+		return true, <-done
+	}
+
+	timer := time.NewTimer(limit)
+	defer timer.Stop()
+
+	select {
+	case err := <-done:
+		return true, err
+	case <-timer.C:
+	}
+
+	// f may have returned right as the limit was reached.
+	select {
+	case err := <-done:
+		return true, err
+	default:
+		return false, nil
+	}
+}
+
+// runWithContext runs f like run, giving it a context. Cleanups get a context
+// that is not cancelled when the run is interrupted, so that they can still
+// release what the run used. It is only cancelled once the guard gives up on
+// them: at the end of the grace period, or at the deadline. Other functions
+// get the context of the run.
+func (g interruptGuard) runWithContext(f func(ctx context.Context) error) (finished bool, err error) {
+	if !g.cleanup {
+		return g.run(func() error { return f(g.ctx) })
+	}
+
+	ctx, cancel := context.WithCancelCause(context.WithoutCancel(g.ctx))
+	defer cancel(nil)
+
+	if !g.deadline.IsZero() {
+		var cancelDeadline context.CancelFunc
+		ctx, cancelDeadline = context.WithDeadline(ctx, g.deadline)
+		defer cancelDeadline()
+	}
+
+	finished, err = g.run(func() error { return f(ctx) })
+	if !finished {
+		cancel(g.interruption())
+	}
+
+	return finished, err
+}
+
+// withDeadline returns a copy of the guard that does not wait past the given
+// deadline, if it is earlier than its own.
+func (g interruptGuard) withDeadline(deadline time.Time) interruptGuard {
+	if !deadline.IsZero() && (g.deadline.IsZero() || deadline.Before(g.deadline)) {
+		g.deadline = deadline
+	}
+
+	return g
+}
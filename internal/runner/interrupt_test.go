@@ -0,0 +1,78 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestInterruptGuardGivesUpAfterGracePeriod(t *testing.T) {
+	cause := errors.New("interrupted")
+	ctx, cancel := context.WithCancelCause(context.Background())
+	guard := interruptGuard{ctx: ctx, grace: 10 * time.Millisecond}
+
+	if guard.interruption() != nil {
+		t.Fatalf("expected no interruption before cancelling")
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+
+	cancel(cause)
+	finished, _ := guard.run(func() error {
+		<-release
+		return nil
+	})
+	if finished {
+		t.Fatalf("expected the guard to give up on the function")
+	}
+
+	if guard.interruption() != cause {
+		t.Fatalf("expected the interruption to be %v, got %v", cause, guard.interruption())
+	}
+
+	finished, err := guard.run(func() error { return cause })
+	if !finished || err != cause {
+		t.Fatalf("expected the function to finish with %v, got %v, %v", cause, finished, err)
+	}
+}
+
+func TestInterruptGuardKeepsCleanupContextAlive(t *testing.T) {
+	cause := errors.New("interrupted")
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(cause)
+
+	guard := interruptGuard{ctx: ctx, grace: 10 * time.Millisecond}.forCleanups()
+	finished, err := guard.runWithContext(func(ctx context.Context) error {
+		return ctx.Err()
+	})
+	if !finished || err != nil {
+		t.Fatalf("expected the cleanup to run with a live context, got %v, %v", finished, err)
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+
+	contexts := make(chan context.Context, 1)
+	finished, _ = guard.runWithContext(func(ctx context.Context) error {
+		contexts <- ctx
+		<-release
+		return nil
+	})
+	if finished {
+		t.Fatalf("expected the guard to give up on the cleanup")
+	}
+
+	cleanupCtx := <-contexts
+	if context.Cause(cleanupCtx) != cause {
+		t.Errorf("expected the context to be cancelled with %v once given up on, got %v", cause, context.Cause(cleanupCtx))
+	}
+
+	finished, err = interruptGuard{ctx: ctx}.runWithContext(func(ctx context.Context) error {
+		return ctx.Err()
+	})
+	if !finished || err == nil {
+		t.Errorf("expected other functions to get the cancelled context of the run, got %v, %v", finished, err)
+	}
+}
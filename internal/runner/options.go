@@ -0,0 +1,75 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/microsoft/storm/internal/artifacts"
+	"github.com/microsoft/storm/pkg/storm/core"
+)
+
+// RunOptions holds the options for running a scenario or helper.
+type RunOptions struct {
+	// Forward the output of the tests to the console in real-time.
+	Watch bool
+
+	// Directory to save logs to, if not nil. When nil, a run directory is
+	// created as described by RunDir.
+	LogDir *string
+
+	// Run directory created when no log directory is given.
+	RunDir RunDirOptions
+
+	// Path to produce a JUnit XML report at, if not nil.
+	JUnitPath *string
+
+	// Path to produce a JSON results file at, if not nil.
+	JSONPath *string
+
+	// Number of lines kept in memory from the start and the end of each test
+	// case's output for reports. The full output is spooled to disk and is
+	// always available in the saved logs.
+	OutputHeadLines int
+	OutputTailLines int
+
+	// How artifacts published by the test cases are stored.
+	Artifacts artifacts.Options
+
+	// Do not run the cleanup of the runnable when its setup fails. By
+	// default, the cleanup runs so that partially provisioned resources are
+	// released.
+	SkipCleanupAfterSetupFailure bool
+
+	// How long the run may last and how it stops.
+	TimeLimits TimeLimitOptions
+
+	// How long the diagnostics collectors of the suite may take in total after
+	// a test case failed or errored, 0 to disable them.
+	DiagnosticsBudget time.Duration
+
+	// Where the progress of the run is saved, and the run it resumes.
+	Checkpoint CheckpointOptions
+
+	// The test cases to run again from an earlier run, if any.
+	Rerun RerunOptions
+}
+
+// prepareOutputFile makes sure that a report of the given kind can be written
+// to the given path, creating the parent directory if needed.
+func prepareOutputFile(suite core.SuiteContext, kind string, outputPath string) error {
+	info, err := os.Stat(outputPath)
+	if err == nil && info.IsDir() {
+		return fmt.Errorf("cannot write %s to '%s': path is a directory", kind, outputPath)
+	}
+
+	outputDir := path.Dir(outputPath)
+	suite.Logger().Infof("Producing %s output at '%s'", kind, outputPath)
+	err = os.MkdirAll(outputDir, 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create %s output directory '%s': %w", kind, outputDir, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package runner
+
+import (
+	"errors"
+
+	"github.com/microsoft/storm/internal/reporter"
+	"github.com/microsoft/storm/pkg/storm/core"
+	"github.com/microsoft/storm/pkg/storm/results"
+)
+
+// reporterList dispatches run events to a list of reporters, in order.
+type reporterList []core.Reporter
+
+// newReporterList creates the list of reporters for a run. Storm's built-in
+// reporters come first, followed by the reporters registered in the suite.
+func newReporterList(suite core.SuiteContext, opts RunOptions) reporterList {
+	reporters := reporterList{reporter.NewConsoleReporter(suite.AzureDevops())}
+
+	if opts.JUnitPath != nil {
+		reporters = append(reporters, reporter.NewJUnitReporter(*opts.JUnitPath))
+	}
+
+	if opts.JSONPath != nil {
+		reporters = append(reporters, reporter.NewJSONReporter(*opts.JSONPath))
+	}
+
+	if opts.LogDir != nil {
+		reporters = append(reporters, reporter.NewLogReporter(*opts.LogDir))
+	}
+
+	return append(reporters, suite.Reporters()...)
+}
+
+// runStarted notifies all reporters that the run has started. It stops at the
+// first reporter that returns an error.
+func (rl reporterList) runStarted(info results.RunInfo) error {
+	for _, r := range rl {
+		err := r.RunStarted(info)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (rl reporterList) testCaseStarted(info results.TestCaseInfo) {
+	for _, r := range rl {
+		r.TestCaseStarted(info)
+	}
+}
+
+func (rl reporterList) testCaseFinished(result results.TestCaseResult) {
+	for _, r := range rl {
+		r.TestCaseFinished(result)
+	}
+}
+
+// runFinished notifies all reporters that the run has finished. All reporters
+// are notified even if some of them fail, the errors are joined together.
+func (rl reporterList) runFinished(result results.RunResult) error {
+	var errs []error
+	for _, r := range rl {
+		err := r.RunFinished(result)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,110 @@
+package runner
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/microsoft/storm/internal/collector"
+	"github.com/microsoft/storm/internal/reporter"
+	"github.com/microsoft/storm/internal/testmgr"
+	"github.com/microsoft/storm/pkg/storm/core"
+	"github.com/microsoft/storm/pkg/storm/results"
+)
+
+// RerunOptions selects the test cases to run again from an earlier run.
+type RerunOptions struct {
+	// Path of a JSON results or JUnit XML file whose failed and errored test
+	// cases are run again, if not nil.
+	Results *string
+
+	// Also run the test cases before the ones that did not pass, which they
+	// may depend on.
+	Dependencies bool
+}
+
+// selectTestCases returns the names of the test cases of the registrant that
+// failed or errored in the earlier run, if any, and still exist. It returns
+// false when there is nothing to run again, so that the run stops before
+// creating its run directory or any reports.
+func (o RerunOptions) selectTestCases(suite core.SuiteContext, registrant interface {
+	core.TestRegistrant
+	core.TestRegistrantMetadata
+}) ([]string, bool, error) {
+	if o.Results == nil {
+		return nil, true, nil
+	}
+
+	runs, err := reporter.ReadResults(*o.Results)
+	if err != nil {
+		return nil, false, err
+	}
+
+	failed, err := failedTestCases(runs, registrant)
+	if err != nil {
+		return nil, false, fmt.Errorf("'%s': %w", *o.Results, err)
+	}
+
+	collected, err := collector.CollectTestCases(registrant)
+	if err != nil {
+		return nil, false, err
+	}
+
+	selected := slices.DeleteFunc(failed, func(name string) bool {
+		exists := slices.ContainsFunc(collected, func(testCase collector.TestCaseMetadata) bool { return testCase.Name == name })
+		if !exists {
+			suite.Logger().Warnf("Not rerunning test case '%s', which no longer exists", name)
+		}
+
+		return !exists
+	})
+
+	if len(selected) == 0 {
+		suite.Logger().Warnf("No failed test cases to rerun from '%s'", *o.Results)
+		return nil, false, nil
+	}
+
+	return selected, true, nil
+}
+
+// apply restricts the run to the test cases selected by selectTestCases.
+func (o RerunOptions) apply(suite core.SuiteContext, testManager *testmgr.StormTestManager, selected []string) {
+	if o.Results == nil {
+		return
+	}
+
+	testManager.Rerun(selected, o.Dependencies)
+	suite.Logger().Infof("Rerunning %d failed test cases from '%s', running %d test cases in total", len(selected), *o.Results, len(testManager.TestCases()))
+}
+
+// failedTestCases returns the names of the test cases of the given registrant
+// that failed or errored in the given runs. When the registrant ran
+// several times, the last status of each test case counts. All test cases of
+// a run whose setup failed count as failed.
+func failedTestCases(runs []results.RunResult, registrant core.TestRegistrantMetadata) ([]string, error) {
+	registrantType := registrant.RegistrantType().String()
+
+	var names []string
+	found := false
+	for _, run := range runs {
+		// JUnit XML files produced by other tools do not have a registrant
+		// type.
+		if run.Registrant != registrant.Name() || run.RegistrantType != registrantType && run.RegistrantType != "unknown" {
+			continue
+		}
+
+		found = true
+		setupFailed := run.Setup != nil && run.Setup.Status.IsBad()
+		for _, testCase := range run.TestCases {
+			names = slices.DeleteFunc(names, func(name string) bool { return name == testCase.Name })
+			if setupFailed || testCase.Status == results.TestCaseStatusFailed || testCase.Status == results.TestCaseStatusError {
+				names = append(names, testCase.Name)
+			}
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("no results for %s '%s'", registrantType, registrant.Name())
+	}
+
+	return names, nil
+}
@@ -0,0 +1,118 @@
+package runner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/microsoft/storm/internal/reporter"
+	"github.com/microsoft/storm/pkg/storm/core"
+	"github.com/microsoft/storm/pkg/storm/results"
+
+	"github.com/sirupsen/logrus"
+)
+
+// rerunSuite provides the little of a suite that a run uses before it starts
+// running test cases.
+type rerunSuite struct {
+	core.SuiteContext
+}
+
+func (s rerunSuite) Name() string { return "suite" }
+
+func (s rerunSuite) Logger() *logrus.Logger { return logrus.New() }
+
+func (s rerunSuite) Context() context.Context { return context.Background() }
+
+type rerunScenario struct {
+	core.BaseScenario
+}
+
+func (s *rerunScenario) Name() string { return "rerun" }
+
+func (s *rerunScenario) RegisterTestCases(r core.TestRegistrar) error {
+	r.RegisterTestCase("deploy", func(core.TestCase) error { return nil })
+	r.RegisterTestCase("check", func(core.TestCase) error { return nil })
+	return nil
+}
+
+func TestFailedTestCases(t *testing.T) {
+	testCase := func(name string, status results.TestCaseStatus) results.TestCaseResult {
+		return results.TestCaseResult{TestCaseInfo: results.TestCaseInfo{Name: name}, Status: status}
+	}
+
+	runs := []results.RunResult{
+		{
+			RunInfo: results.RunInfo{RegistrantType: "scenario", Registrant: "limited"},
+			TestCases: []results.TestCaseResult{
+				testCase("deploy", results.TestCaseStatusPassed),
+				testCase("flaky", results.TestCaseStatusFailed),
+				testCase("broken", results.TestCaseStatusError),
+				testCase("later", results.TestCaseStatusNotRun),
+			},
+		},
+		{
+			RunInfo:   results.RunInfo{RegistrantType: "helper", Registrant: "limited"},
+			TestCases: []results.TestCaseResult{testCase("deploy", results.TestCaseStatusFailed)},
+		},
+		{
+			RunInfo:   results.RunInfo{RegistrantType: "unknown", Registrant: "limited"},
+			TestCases: []results.TestCaseResult{testCase("broken", results.TestCaseStatusPassed)},
+		},
+	}
+
+	scenario := &runnableInstance{TestRegistrant: &timeLimitedScenario{}}
+	failed, err := failedTestCases(runs, scenario)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !slices.Equal(failed, []string{"flaky"}) {
+		t.Errorf("expected the last failures of the scenario, got %v", failed)
+	}
+
+	runs[0].Setup = &results.TestCaseResult{Status: results.TestCaseStatusError}
+	failed, _ = failedTestCases(runs[:1], scenario)
+	if len(failed) != 4 {
+		t.Errorf("expected all test cases to be rerun after a setup failure, got %v", failed)
+	}
+
+	_, err = failedTestCases(runs[1:2], scenario)
+	if err == nil {
+		t.Errorf("expected an error without results for the scenario")
+	}
+}
+
+func TestRerunWithoutFailures(t *testing.T) {
+	dir := t.TempDir()
+	resultsPath := filepath.Join(dir, "results.json")
+	err := reporter.WriteJSON(resultsPath, []results.RunResult{{
+		RunInfo: results.RunInfo{RegistrantType: "scenario", Registrant: "rerun"},
+		TestCases: []results.TestCaseResult{
+			{TestCaseInfo: results.TestCaseInfo{Name: "deploy"}, Status: results.TestCaseStatusPassed},
+			{TestCaseInfo: results.TestCaseInfo{Name: "removed"}, Status: results.TestCaseStatusFailed},
+		},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runsRoot := filepath.Join(dir, "runs")
+	jsonPath := filepath.Join(dir, "out", "results.json")
+	err = RegisterAndRunTests(rerunSuite{}, &rerunScenario{}, nil, RunOptions{
+		JSONPath: &jsonPath,
+		RunDir:   RunDirOptions{Root: runsRoot},
+		Rerun:    RerunOptions{Results: &resultsPath},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	for _, path := range []string{runsRoot, filepath.Dir(jsonPath)} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("expected '%s' not to be created when nothing is rerun", path)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/microsoft/storm/pkg/storm/core"
+)
+
+// Name of the symbolic link pointing to the most recent run directory.
+const latestRunLink = "latest"
+
+// Time format prefixing the names of run directories, which makes them sort
+// chronologically.
+const runDirTimeFormat = "20060102-150405"
+
+// Matches the names of run directories created by storm.
+var runDirRegex = regexp.MustCompile(`^\d{8}-\d{6}-`)
+
+// RunDirOptions controls the run directory created when no log directory is
+// given.
+type RunDirOptions struct {
+	// Do not create a run directory.
+	Disabled bool
+
+	// Directory the run directories are created in. Defaults to a directory
+	// named after the suite in the user's cache directory.
+	Root string
+
+	// Number of run directories to keep, including the new one. Older ones
+	// are removed. 0 keeps all of them.
+	Keep int
+}
+
+// defaultRunsRoot returns the default directory run directories are created
+// in.
+func defaultRunsRoot(suite core.SuiteContext) (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the user cache directory: %w", err)
+	}
+
+	return filepath.Join(cacheDir, "storm", suite.Name(), "runs"), nil
+}
+
+// createRunDir creates a new timestamped run directory for the given
+// registrant, points the latest link of the root at it and removes the run
+// directories beyond the retention limit. It returns the path of the new run
+// directory.
+func createRunDir(suite core.SuiteContext, registrant string, opts RunDirOptions) (string, error) {
+	root := opts.Root
+	if root == "" {
+		var err error
+		root, err = defaultRunsRoot(suite)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	err := os.MkdirAll(root, 0755)
+	if err != nil {
+		return "", fmt.Errorf("failed to create runs directory '%s': %w", root, err)
+	}
+
+	name := fmt.Sprintf("%s-%s", time.Now().Format(runDirTimeFormat), registrant)
+	dir := filepath.Join(root, name)
+	for i := 2; ; i++ {
+		err = os.Mkdir(dir, 0755)
+		if !os.IsExist(err) {
+			break
+		}
+
+		dir = filepath.Join(root, fmt.Sprintf("%s-%d", name, i))
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("failed to create run directory '%s': %w", dir, err)
+	}
+
+	// Failing to maintain the root is not worth failing the run for.
+	err = updateLatestRunLink(root, filepath.Base(dir))
+	if err != nil {
+		suite.Logger().Warnf("Failed to update the latest run link: %v", err)
+	}
+
+	if opts.Keep > 0 {
+		err = pruneRunDirs(root, opts.Keep)
+		if err != nil {
+			suite.Logger().Warnf("Failed to remove old run directories: %v", err)
+		}
+	}
+
+	return dir, nil
+}
+
+// updateLatestRunLink points the latest link in root at the given run
+// directory, replacing it atomically.
+func updateLatestRunLink(root string, name string) error {
+	link := filepath.Join(root, latestRunLink)
+	tmp := link + ".tmp"
+	os.Remove(tmp)
+
+	err := os.Symlink(name, tmp)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, link)
+}
+
+// pruneRunDirs removes the oldest run directories in root so that at most keep
+// of them remain. Only directories named like run directories are considered.
+func pruneRunDirs(root string, keep int) error {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return err
+	}
+
+	var runDirs []string
+	for _, entry := range entries {
+		if entry.IsDir() && runDirRegex.MatchString(entry.Name()) {
+			runDirs = append(runDirs, entry.Name())
+		}
+	}
+
+	if len(runDirs) <= keep {
+		return nil
+	}
+
+	slices.Sort(runDirs)
+	for _, name := range runDirs[:len(runDirs)-keep] {
+		err = os.RemoveAll(filepath.Join(root, name))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
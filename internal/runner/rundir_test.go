@@ -0,0 +1,34 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestPruneRunDirs(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"20250101-000000-a", "20250102-000000-b", "20250103-000000-a", "other", latestRunLink} {
+		err := os.Mkdir(filepath.Join(root, name), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := pruneRunDirs(root, 2)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	entries, _ := os.ReadDir(root)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+
+	expected := []string{"20250102-000000-b", "20250103-000000-a", latestRunLink, "other"}
+	if !slices.Equal(names, expected) {
+		t.Errorf("expected %v to remain, got %v", expected, names)
+	}
+}
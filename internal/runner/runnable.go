@@ -19,3 +19,23 @@ func (ri *runnableInstance) RegistrantType() core.RegistrantType {
 	panic("unknown runnable type")
 
 }
+
+// Tags returns the tags of the scenario, or nil if the runnable is not a
+// scenario.
+func (ri *runnableInstance) Tags() []string {
+	if scenario, ok := ri.TestRegistrant.(core.Scenario); ok {
+		return scenario.Tags()
+	}
+
+	return nil
+}
+
+// StagePaths returns the stage paths of the scenario, or nil if the runnable is
+// not a scenario.
+func (ri *runnableInstance) StagePaths() []string {
+	if scenario, ok := ri.TestRegistrant.(core.Scenario); ok {
+		return scenario.StagePaths()
+	}
+
+	return nil
+}
@@ -1,160 +1,301 @@
 package runner
 
 import (
-	"bufio"
+	"context"
 	"fmt"
 	"io"
 	"os"
-	"path"
 	"runtime"
 	"runtime/debug"
 	"slices"
-	"sync"
+	"time"
 
+	"github.com/microsoft/storm/internal/artifacts"
 	"github.com/microsoft/storm/internal/reporter"
 	"github.com/microsoft/storm/internal/stormerror"
 	"github.com/microsoft/storm/internal/testmgr"
 	"github.com/microsoft/storm/pkg/storm/core"
-
-	"github.com/sirupsen/logrus"
+	"github.com/microsoft/storm/pkg/storm/results"
 )
 
 // RegisterAndRunTests registers the tests from the given registrant and runs
 // them. It takes care of argument parsing, setting up the test manager, and
-// producing reports. If watch is true, the output of the tests is forwarded to
-// the console in real-time. If logDir is not nil, logs are saved to the given
-// directory. If junitPath is not nil, a JUnit XML report is produced at the
-// given path.
+// notifying the built-in reporters and those registered in the suite. See
+// RunOptions for the available options.
 func RegisterAndRunTests(suite core.SuiteContext,
 	registrant interface {
 		core.Argumented
 		core.TestRegistrant
 	},
 	args []string,
-	watch bool,
-	logDir *string,
-	junitPath *string,
+	opts RunOptions,
 ) error {
+	// Past its time limit, the run is stopped through the suite context.
+	suite, guards, releaseTimeLimit, err := limitRunTime(suite, registrant, opts.TimeLimits)
+	if err != nil {
+		return err
+	}
+	defer releaseTimeLimit()
+
 	// Create a new runnable instance
 	registrantInstance := &runnableInstance{
 		TestRegistrant: registrant,
 		Argumented:     registrant,
 	}
 
-	// Prepare the junit output directory if needed
-	if junitPath != nil {
-		junitPath := *junitPath
-		info, err := os.Stat(junitPath)
-		if err == nil && info.IsDir() {
-			return fmt.Errorf("cannot write JUnit XML to '%s': path is a directory", junitPath)
+	// Parse the extra arguments for the runnable
+	args = stripPassthroughSeparator(args)
+	err = parseExtraArguments(suite, args, registrantInstance)
+	if err != nil {
+		return err
+	}
+
+	// Nothing is run, saved or reported when no test case failed in the run
+	// to rerun.
+	rerun, ok, err := opts.Rerun.selectTestCases(suite, registrantInstance)
+	if err != nil {
+		return fmt.Errorf("failed to select test cases to rerun: %w", err)
+	}
+
+	if !ok {
+		return nil
+	}
+
+	// Prepare the output directories of the reports if needed
+	if opts.JUnitPath != nil {
+		err := prepareOutputFile(suite, "JUnit XML", *opts.JUnitPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	if opts.JSONPath != nil {
+		err := prepareOutputFile(suite, "JSON results", *opts.JSONPath)
+		if err != nil {
+			return err
 		}
+	}
 
-		junitDir := path.Dir(junitPath)
-		suite.Logger().Infof("Producing JUnit XML output at '%s'", junitPath)
-		err = os.MkdirAll(junitDir, 0755)
+	// Without a log directory, logs and artifacts go to a new run directory
+	// so that they are not lost. Runs without one still work as they used to.
+	if opts.LogDir == nil && !opts.RunDir.Disabled {
+		runDir, err := createRunDir(suite, registrant.Name(), opts.RunDir)
 		if err != nil {
-			return fmt.Errorf("failed to create JUnit output directory '%s': %w", junitDir, err)
+			suite.Logger().Warnf("Not saving logs: %v", err)
+		} else {
+			opts.LogDir = &runDir
 		}
 	}
 
 	// Prepare the log directory if needed
-	if logDir != nil {
-		suite.Logger().Infof("Saving logs to '%s'", *logDir)
-		err := os.MkdirAll(*logDir, 0755)
+	if opts.LogDir != nil {
+		suite.Logger().Infof("Saving logs to '%s'", *opts.LogDir)
+		err := os.MkdirAll(*opts.LogDir, 0755)
 		if err != nil {
-			return fmt.Errorf("failed to create log directory '%s': %w", *logDir, err)
+			return fmt.Errorf("failed to create log directory '%s': %w", *opts.LogDir, err)
 		}
 	}
 
-	// Parse the extra arguments for the runnable
-	err := parseExtraArguments(suite, args, registrantInstance)
-	if err != nil {
-		return err
+	// The storage given on the command line takes precedence over the one set
+	// in the suite.
+	if opts.Artifacts.Storage == nil {
+		opts.Artifacts.Storage = suite.ArtifactStorage()
 	}
 
 	// Create a new test manager for the runnable
-	testMgr, err := testmgr.NewStormTestManager(suite, registrantInstance, logDir)
+	testMgr, err := testmgr.NewStormTestManager(suite, registrantInstance, args, opts.LogDir, opts.Artifacts)
 	if err != nil {
 		return fmt.Errorf("failed to create test manager: %w", err)
 	}
 
-	// Actually run the thing
-	err = executeTestCases(suite, registrantInstance, testMgr, watch)
-	testMgr.StopTimer()
+	err = opts.Checkpoint.resume(suite, testMgr)
+	if err != nil {
+		return fmt.Errorf("failed to resume run: %w", err)
+	}
+
+	opts.Rerun.apply(suite, testMgr, rerun)
+
+	// The full output of the test cases is spooled to a temporary directory,
+	// which is only needed until the reporters are done.
+	spoolDir, err := os.MkdirTemp("", "storm-output-")
+	if err != nil {
+		return fmt.Errorf("failed to create output spool directory: %w", err)
+	}
+	defer os.RemoveAll(spoolDir)
+
+	capture := captureOptions{
+		spoolDir:  spoolDir,
+		headLines: opts.OutputHeadLines,
+		tailLines: opts.OutputTailLines,
+		forward: func(w io.Writer, line results.OutputLine) {
+			if (suite.AzureDevops() || opts.Watch) && reporter.OutputLineVisible(line, suite.Logger().GetLevel()) {
+				fmt.Fprintf(w, "  ├ %s\n", line.Text)
+			}
+		},
+	}
+
+	reporters := newReporterList(suite, opts)
+	if path, ok := opts.Checkpoint.path(opts.LogDir); ok {
+		reporters = append(reporters, newCheckpointReporter(suite, path, testMgr))
+	}
+
+	err = reporters.runStarted(reporter.NewRunInfo(testMgr))
 	if err != nil {
-		switch err.(type) {
+		return fmt.Errorf("failed to start reporters: %w", err)
+	}
+
+	// Actually run the thing. Artifacts published through the suite meanwhile
+	// belong to this run.
+	restoreSuiteBroker := artifacts.SetActiveSuiteBroker(testMgr.SuiteArtifactBroker())
+	steps, runErr := executeTestCases(suite, registrantInstance, testMgr, reporters, capture, guards, opts.DiagnosticsBudget, !opts.SkipCleanupAfterSetupFailure)
+	restoreSuiteBroker()
+
+	// Scenario fixtures are torn down after the cleanup, their errors are
+	// reported with the fixtures.
+	guards.cleanup.run(testMgr.TeardownFixtures)
+
+	testMgr.SuiteArtifactBroker().Finish()
+	testMgr.StopTimer()
+
+	// Artifacts are uploaded in the background while the following test cases
+	// run, the results must wait for their URLs.
+	finished, _ := guards.cleanup.run(func() error {
+		testMgr.WaitArtifactUploads()
+		return nil
+	})
+	if !finished {
+		suite.Logger().Warnf("Gave up waiting for artifact uploads: %v", guards.cleanup.interruption())
+	}
+
+	result := reporter.NewRunResult(testMgr)
+
+	// The setup and cleanup are reported along with the test cases.
+	if steps.setup != nil {
+		result.Setup = steps.setup
+		result.Setup.Artifacts = testMgr.SetupArtifactBroker().Published()
+	}
+
+	if steps.cleanup != nil {
+		result.Cleanup = steps.cleanup
+		result.Cleanup.Artifacts = testMgr.CleanupArtifactBroker().Published()
+	}
+
+	if runErr != nil {
+		switch e := runErr.(type) {
 		case *setupError:
-			// If setup failed we have no test results to report, we can just
-			// exist now.
-			return err
+			// If setup failed no test case ran, but we still report the
+			// failure so that it is visible in CI.
+			suite.Logger().Error(e)
 		case *cleanupError:
 			// If cleanup failed we still want to report the test results.
-			suite.Logger().Error(err)
+			suite.Logger().Error(e)
 		default:
 			// Unknown error, log it and continue.
-			suite.Logger().WithError(err).Error("Unknown error occurred!")
+			suite.Logger().WithError(runErr).Error("Unknown error occurred!")
 		}
 	}
 
-	rep := reporter.NewTestReporter(testMgr)
-
-	rep.PrintReport()
+	err = reporters.runFinished(result)
+	if err != nil {
+		return err
+	}
 
-	if junitPath != nil {
-		err := rep.ProduceJUnitXML(*junitPath)
-		if err != nil {
-			return fmt.Errorf("failed to produce JUnit XML at '%s': %w", *junitPath, err)
-		}
+	// An interrupted run is reported as such, whatever its results.
+	if cause := guards.cleanup.interruption(); cause != nil {
+		return cause
 	}
 
-	if logDir != nil {
-		err = rep.SaveLogs(*logDir)
-		if err != nil {
-			return fmt.Errorf("failed to save logs to '%s': %w", *logDir, err)
-		}
+	// A setup error is more relevant than the test results, which will all be
+	// not run.
+	if e, ok := runErr.(*setupError); ok {
+		return e
 	}
 
-	return rep.ExitError()
+	return reporter.NewTestReporter(result, suite.AzureDevops()).ExitError()
 }
 
 // executeTestCases runs all test cases in the given test manager. It takes
 // care of calling setup and cleanup methods if the runnable implements the
-// SetupCleanup interface. If watch is true, the output of the tests is
-// forwarded to the console in real-time.
+// SetupCleanup interface, and returns their results. When the setup fails, the
+// cleanup only runs if cleanupAfterSetupFailure is set. Reporters are notified
+// as each test case starts and finishes. The output of each test case, setup
+// and cleanup is captured as described by capture.
+//
+// When the run is interrupted, the running test case errors out, the remaining
+// ones are not run and the cleanups still run, each of them being waited for as
+// described by guards. Diagnostics are collected within diagnosticsBudget after
+// each test case that failed or errored.
 func executeTestCases(suite core.SuiteContext,
 	runnable *runnableInstance,
 	testManager *testmgr.StormTestManager,
-	watch bool,
-) error {
-
-	ctx := &runnableContext{
-		LoggerProvider:         suite,
-		TestRegistrantMetadata: runnable,
-	}
+	reporters reporterList,
+	capture captureOptions,
+	guards runGuards,
+	diagnosticsBudget time.Duration,
+	cleanupAfterSetupFailure bool,
+) (stepResults, error) {
+	var steps stepResults
 
 	// If the runnable implements the SetupCleanup interface, we call
 	// the setup method before running the tests.
 	if r, ok := runnable.TestRegistrant.(core.SetupCleanup); ok {
-		err := runCatchPanic(func() error { return r.Setup(ctx) })
+		result, err := runStep(suite, runnable, setupStepName, testManager.SetupArtifactBroker(), capture, guards.testCase, r.Setup)
+		steps.setup = result
+		if result == nil {
+			return steps, err
+		}
+
 		if err != nil {
-			return newSetupError(runnable, err)
+			// None of the test cases can run without a successful setup.
+			for i, testCase := range testManager.TestCases() {
+				testCase.MarkNotRun(fmt.Sprintf("setup failure: %v", err))
+				reporters.testCaseFinished(reporter.NewTestCaseResult(testCase, i))
+			}
+
+			// The setup may have been partially done, the cleanup releases
+			// whatever it provisioned. The setup error remains the error of
+			// the run.
+			if cleanupAfterSetupFailure {
+				steps.cleanup = cleanupAfterFailedSetup(suite, runnable, testManager, capture, guards.cleanup, r, err)
+			}
+
+			return steps, newSetupError(runnable, err)
 		}
 	}
 
-	cleanupFuncs := make([]func(), 0)
+	cleanupFuncs := make([]func(context.Context), 0)
+	hooks := newTestCaseHooks(suite, runnable)
+	diagnostics := newDiagnostics(suite, diagnosticsBudget)
 
 	bail := false
 
-	for _, testCase := range testManager.TestCases() {
+	for i, testCase := range testManager.TestCases() {
+		// Test cases that passed in the resumed run are not run again.
+		if testCase.PreviouslyPassed() {
+			testCase.MarkPreviouslyPassed()
+			suite.Logger().Infof("%s %s (previously passed)", testCase.Name(), testCase.Status().ColorString())
+			reporters.testCaseFinished(reporter.NewTestCaseResult(testCase, i))
+			continue
+		}
+
+		// Once the run is interrupted, none of the remaining test cases run.
+		if cause := guards.testCase.interruption(); cause != nil {
+			testCase.MarkNotRun(cause.Error())
+			reporters.testCaseFinished(reporter.NewTestCaseResult(testCase, i))
+			continue
+		}
+
 		// If bail is true, we are no longer running tests. Mark this test case
 		// as not run and 'continue' to iterate over all remaining test cases to
 		// mark them as not run.
 		if bail {
 			testCase.MarkNotRun("dependency failure")
+			reporters.testCaseFinished(reporter.NewTestCaseResult(testCase, i))
 			continue
 		}
 
 		suite.Logger().Infof("%s (started)", testCase.Name())
+		reporters.testCaseStarted(reporter.NewTestCaseInfo(testCase, i))
 
 		// Capture the number of goroutines before running the test case.
 		// After the test case has run, we compare the number of goroutines to
@@ -164,28 +305,28 @@ func executeTestCases(suite core.SuiteContext,
 		var startGoroutines = runtime.NumGoroutine()
 
 		// Call the captureOutput function to run the test case and capture its
-		// output. We also forward the output to the console if we are running
-		// in watch mode or in Azure DevOps.
-		captured, err := captureOutput(func() {
-			executeTestCase(testCase)
-		}, func(w io.Writer, s string) {
-			if suite.AzureDevops() || watch {
-				fmt.Fprintf(w, "  ├ %s\n", s)
-			}
+		// output. The output is also forwarded to the console if we are
+		// running in watch mode or in Azure DevOps.
+		captured, err := captureOutput(capture, func(sink func(results.OutputLine)) {
+			testCase.SetOutputSink(sink)
+			defer testCase.SetOutputSink(nil)
+			executeTestCase(testCase, guards.testCase, hooks, diagnostics)
 		})
 
 		// Calculate the difference in goroutine count.
 		delta := runtime.NumGoroutine() - startGoroutines
 
-		// Store the captured output in the test case.
-		testCase.SetCollectedOutput(captured)
-
 		// If we failed to collect the output, return an error. This means
 		// that we didn't even run.
 		if err != nil {
-			return fmt.Errorf("failed to capture output for '%s': %w", testCase.Name(), err)
+			return steps, fmt.Errorf("failed to capture output for '%s': %w", testCase.Name(), err)
 		}
 
+		// Store the captured output in the test case.
+		testCase.SetCollectedOutput(captured.lines)
+		testCase.SetOutputSpool(captured.file, captured.omitted)
+		testCase.FinishArtifacts()
+
 		// Grab and store the cleanup functions for this test case.
 		cleanupFuncs = append(cleanupFuncs, testCase.SuiteCleanupList()...)
 
@@ -194,6 +335,7 @@ func executeTestCases(suite core.SuiteContext,
 
 		// Output the test case status.
 		suite.Logger().Infof("%s %s", testCase.Name(), testCase.Status().ColorString())
+		reporters.testCaseFinished(reporter.NewTestCaseResult(testCase, i))
 
 		// Print a warning if we suspect the test case has leaked goroutines.
 		if delta > 0 {
@@ -205,22 +347,30 @@ func executeTestCases(suite core.SuiteContext,
 	// If we have any cleanup functions, run them in reverse order.
 	slices.Reverse(cleanupFuncs)
 	for _, f := range cleanupFuncs {
-		runCatchPanic(func() error {
-			f()
+		finished, _ := guards.cleanup.runWithContext(func(ctx context.Context) error {
+			f(ctx)
 			return nil
 		})
+		if !finished {
+			suite.Logger().Warnf("Gave up waiting for a suite cleanup function: %v", guards.cleanup.interruption())
+		}
 	}
 
 	// If the runnable implements the SetupCleanup interface, we call
 	// the Cleanup method after running the tests.
 	if r, ok := runnable.TestRegistrant.(core.SetupCleanup); ok {
-		err := runCatchPanic(func() error { return r.Cleanup(ctx) })
+		result, err := runStep(suite, runnable, cleanupStepName, testManager.CleanupArtifactBroker(), capture, guards.cleanup, r.Cleanup)
+		steps.cleanup = result
+		if result == nil {
+			return steps, err
+		}
+
 		if err != nil {
-			return newCleanupError(runnable, err)
+			return steps, newCleanupError(runnable, err)
 		}
 	}
 
-	return nil
+	return steps, nil
 }
 
 // executeTestCase runs the given test case in a standalone goroutine to support
@@ -229,34 +379,53 @@ func executeTestCases(suite core.SuiteContext,
 // as appropriate.
 //
 // If the test case finishes without error and is still marked as running, it is
-// marked as passed. Otherwise, if the test case panicked or returned an error,
-// it is marked as errored.
-func executeTestCase(testCase *testmgr.TestCase) {
-	var err error
-	var wg sync.WaitGroup
+// marked as passed once its fixtures are torn down. Otherwise, if the test case
+// panicked or returned an error, it is marked as errored. A test case still
+// running when the run is interrupted is marked as errored with the
+// interruption.
+//
+// The given hooks run before and after the test case; the test case only runs
+// if the hooks before it succeed. Diagnostics are collected if the test case
+// failed or errored, while its fixtures are still set up.
+func executeTestCase(testCase *testmgr.TestCase, guard interruptGuard, hooks testCaseHooks, diagnostics diagnostics) {
+	testCase.Start()
 
 	// Run the runnable in a separate goroutine to so that runtime.Goexit() can
-	// be called to stop the test execution.
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-
-		// Catch any panic that occurs during the execution of the test case and
-		// convert it to an error with runCatchPanic.
-		err = runCatchPanic(func() error {
-			return testCase.Execute()
-		})
-	}()
+	// be called to stop the test execution. Panics are converted to errors.
+	finished, err := true, error(nil)
+	if hooks.runBefore(testCase, guard) {
+		finished, err = guard.run(testCase.Execute)
+	}
 
-	// Wait for the goroutine to finish and close the test with whatever
-	// error we receive, if any.
-	wg.Wait()
+	// Whatever the test case returned after the interruption is most likely
+	// caused by it.
+	if cause := guard.interruption(); cause != nil && testCase.Status().IsRunning() {
+		err = cause
+		if !finished {
+			err = fmt.Errorf("%w, gave up waiting for the test case", cause)
+		}
+	}
 
 	if err != nil {
 		testCase.MarkError(err)
-	} else if testCase.Status().IsRunning() {
-		testCase.Pass()
 	}
+
+	diagnostics.collect(testCase, guard)
+
+	// The fixtures of the test case are torn down even if it was given up on,
+	// so that they are released. A failed teardown errors out a test case
+	// that was not closed otherwise, which is then diagnosed as well.
+	_, teardownErr := guard.run(testCase.TeardownFixtures)
+	if testCase.Status().IsRunning() {
+		if teardownErr != nil {
+			testCase.MarkError(teardownErr)
+			diagnostics.collect(testCase, guard)
+		} else {
+			testCase.Pass()
+		}
+	}
+
+	hooks.runAfter(testCase, guard)
 }
 
 // runCatchPanic runs the given function f and catches any panic that occurs
@@ -272,82 +441,3 @@ func runCatchPanic(f func() error) (err error) {
 
 	return f()
 }
-
-// captureOutput runs the given function f while capturing all output to
-// stdout and stderr. The captured output is returned as a slice of strings,
-// one per line. The forward function is called for each line of output, which
-// can be used to forward the output to another writer (e.g. the console).
-// The forward function is called synchronously, so it should not block for
-// too long. The function returns an error if it fails to capture the output.
-func captureOutput(f func(), forward func(io.Writer, string)) ([]string, error) {
-	oldStdout := os.Stdout
-	oldStderr := os.Stderr
-
-	rOut, wOut, err := os.Pipe()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create stdout capture pipe: %w", err)
-	}
-
-	rErr, wErr, err := os.Pipe()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create stderr capture pipe: %w", err)
-	}
-
-	os.Stdout = wOut
-	os.Stderr = wErr
-
-	logrusOutput := logrus.StandardLogger().Out
-	logrusFormatter := logrus.StandardLogger().Formatter
-	logrusLevel := logrus.StandardLogger().Level
-
-	// Logrust's standard logger is created on startup and stores a reference to
-	// the real stderr then, so our clever redirection does not work. To enable it, we
-	// need to set the output of the logger to our pipe as well.
-	logrus.SetOutput(os.Stderr)
-
-	// Trick logrus into treating our pipe as the real stderr and force it to TRACE level.
-	logrus.SetFormatter(&logrus.TextFormatter{
-		ForceColors: true,
-	})
-	logrus.SetLevel(logrus.TraceLevel)
-
-	defer func() {
-		os.Stdout = oldStdout
-		os.Stderr = oldStderr
-
-		// Restore the original logrus configuration
-		logrus.SetOutput(logrusOutput)
-		logrus.SetFormatter(logrusFormatter)
-		logrus.SetLevel(logrusLevel)
-	}()
-
-	var combinedOutput []string
-	var outMutex sync.Mutex
-	var wg sync.WaitGroup
-
-	var streamReader = func(r io.Reader, w io.Writer) {
-		defer wg.Done()
-		scanner := bufio.NewScanner(r)
-		for scanner.Scan() {
-			line := scanner.Text()
-			outMutex.Lock()
-			combinedOutput = append(combinedOutput, line)
-			outMutex.Unlock()
-			forward(w, line)
-		}
-	}
-
-	wg.Add(2)
-
-	go streamReader(rOut, oldStdout)
-	go streamReader(rErr, oldStderr)
-
-	f()
-
-	wOut.Close()
-	wErr.Close()
-
-	wg.Wait()
-
-	return combinedOutput, nil
-}
@@ -0,0 +1,121 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/microsoft/storm/internal/artifacts"
+	"github.com/microsoft/storm/internal/stormerror"
+	"github.com/microsoft/storm/internal/testmgr"
+	"github.com/microsoft/storm/pkg/storm/core"
+	"github.com/microsoft/storm/pkg/storm/results"
+)
+
+// Names of the setup and cleanup steps in results and saved logs.
+const (
+	setupStepName   = "setup"
+	cleanupStepName = "cleanup"
+)
+
+// stepResults holds the results of the setup and cleanup steps of a run, when
+// the runnable has them.
+type stepResults struct {
+	setup   *results.TestCaseResult
+	cleanup *results.TestCaseResult
+}
+
+// runStep runs the setup or cleanup step with the given name, capturing its
+// output like a test case's. The context given to f logs into the captured
+// output and publishes artifacts through the given broker, and has the context
+// given by guard. f is waited for as described by guard. It returns the result of the step and the error returned
+// by f.
+func runStep(suite core.SuiteContext,
+	runnable *runnableInstance,
+	name string,
+	broker *artifacts.ArtifactBroker,
+	capture captureOptions,
+	guard interruptGuard,
+	f func(core.SetupCleanupContext) error,
+) (*results.TestCaseResult, error) {
+	suite.Logger().Infof("%s (started)", name)
+
+	var err error
+	startTime := time.Now()
+	captured, captureErr := captureOutput(capture, func(sink func(results.OutputLine)) {
+		ctx := &runnableContext{
+			TestRegistrantMetadata: runnable,
+			LoggerProvider: stepLoggers{
+				logger:     testmgr.NewCaptureLogger(sink),
+				slogLogger: suite.SlogLogger(),
+			},
+			broker: broker,
+		}
+
+		finished, stepErr := guard.runWithContext(func(stepCtx context.Context) error {
+			ctx.ctx = stepCtx
+			return f(ctx)
+		})
+		if !finished {
+			stepErr = fmt.Errorf("%w, gave up waiting for the %s", guard.interruption(), name)
+		}
+		err = stepErr
+	})
+	endTime := time.Now()
+
+	broker.Finish()
+
+	if captureErr != nil {
+		return nil, fmt.Errorf("failed to capture output for %s: %w", name, captureErr)
+	}
+
+	result := &results.TestCaseResult{
+		TestCaseInfo: results.TestCaseInfo{
+			Name:  name,
+			Index: -1,
+		},
+		Status:        results.TestCaseStatusPassed,
+		StartTime:     startTime,
+		Duration:      endTime.Sub(startTime),
+		Output:        captured.lines,
+		OutputOmitted: captured.omitted,
+		OutputFile:    captured.file,
+	}
+
+	if err != nil {
+		result.Status = results.TestCaseStatusError
+		result.Reason = err.Error()
+		if pe, ok := err.(stormerror.PanicError); ok {
+			result.Stack = string(pe.Stack)
+		}
+	}
+
+	suite.Logger().Infof("%s %s", name, result.Status.ColorString())
+	return result, err
+}
+
+// cleanupAfterFailedSetup runs the cleanup step after the setup failed with
+// setupErr, through the runnable's SetupFailureCleanup hook when it has one.
+// Errors of the cleanup are logged, and returned in its result.
+func cleanupAfterFailedSetup(suite core.SuiteContext,
+	runnable *runnableInstance,
+	testManager *testmgr.StormTestManager,
+	capture captureOptions,
+	guard interruptGuard,
+	r core.SetupCleanup,
+	setupErr error,
+) *results.TestCaseResult {
+	cleanup := r.Cleanup
+	if h, ok := runnable.TestRegistrant.(core.SetupFailureCleanup); ok {
+		cleanup = func(ctx core.SetupCleanupContext) error {
+			return h.CleanupAfterSetupFailure(ctx, setupErr)
+		}
+	}
+
+	result, err := runStep(suite, runnable, cleanupStepName, testManager.CleanupArtifactBroker(), capture, guard, cleanup)
+	if err != nil {
+		suite.Logger().Error(newCleanupError(runnable, err))
+	}
+
+	return result
+}
@@ -0,0 +1,118 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/microsoft/storm/internal/stormerror"
+	"github.com/microsoft/storm/pkg/storm/core"
+)
+
+// TimeLimitOptions holds the options controlling how long a run may last and
+// how it stops.
+type TimeLimitOptions struct {
+	// Maximum duration of the run, if not nil. When nil, the runnable's own
+	// limit applies if it implements core.TimeLimited. Zero disables the limit.
+	MaxDuration *time.Duration
+
+	// Time reserved at the end of the maximum duration for the cleanups and
+	// the reports: the run is stopped that long before the maximum duration.
+	// Zero reserves the grace period, capped to half of the maximum duration.
+	CleanupReserve time.Duration
+
+	// How long the running test case, setup, cleanup and suite cleanup
+	// functions are each waited for once the run is interrupted, before giving
+	// up on them to write the reports. Zero waits indefinitely.
+	GracePeriod time.Duration
+}
+
+// maxDuration returns the maximum duration of a run of the given runnable,
+// zero if it is unlimited.
+func (o TimeLimitOptions) maxDuration(runnable core.TestRegistrant) time.Duration {
+	if o.MaxDuration != nil {
+		return *o.MaxDuration
+	}
+
+	if r, ok := runnable.(core.TimeLimited); ok {
+		return r.MaxDuration()
+	}
+
+	return 0
+}
+
+// cleanupReserve returns the time reserved for the cleanups and the reports
+// at the end of the given maximum duration.
+func (o TimeLimitOptions) cleanupReserve(maxDuration time.Duration) (time.Duration, error) {
+	if o.CleanupReserve == 0 {
+		return min(o.GracePeriod, maxDuration/2), nil
+	}
+
+	if o.CleanupReserve < 0 || o.CleanupReserve >= maxDuration {
+		return 0, fmt.Errorf("the cleanup reserve of %s must be positive and shorter than the maximum duration of %s", o.CleanupReserve, maxDuration)
+	}
+
+	return o.CleanupReserve, nil
+}
+
+// runGuards holds the guards waiting for the parts of a run, which may have
+// different deadlines.
+type runGuards struct {
+	testCase interruptGuard
+	cleanup  interruptGuard
+}
+
+// limitedSuite is a suite context whose context is cancelled when the run
+// exceeds its maximum duration. Everything created from it during the run,
+// such as the contexts of the test cases, is then cancelled too.
+type limitedSuite struct {
+	core.SuiteContext
+	ctx context.Context
+}
+
+func (s *limitedSuite) Context() context.Context {
+	return s.ctx
+}
+
+// limitRunTime applies the time limits of a run of the given runnable. It
+// returns the suite context to run with, the guards waiting for the cleanups
+// and the test cases of the run, and a function releasing the resources of the
+// time limit once the run is over.
+//
+// Once the maximum duration minus the cleanup reserve has elapsed, the context
+// of the suite is cancelled. The running test case is then waited for during
+// half of the reserve at most, and the cleanups until the maximum duration.
+func limitRunTime(suite core.SuiteContext, runnable core.TestRegistrant, opts TimeLimitOptions) (core.SuiteContext, runGuards, func(), error) {
+	guard := interruptGuard{ctx: suite.Context(), grace: opts.GracePeriod}
+	guards := runGuards{cleanup: guard.forCleanups(), testCase: guard}
+
+	maxDuration := opts.maxDuration(runnable)
+	if maxDuration <= 0 {
+		return suite, guards, func() {}, nil
+	}
+
+	reserve, err := opts.cleanupReserve(maxDuration)
+	if err != nil {
+		return nil, guards, nil, err
+	}
+
+	suite.Logger().Infof("Limiting the run to %s, keeping %s of it to clean up", maxDuration, reserve)
+
+	ctx, cancel := context.WithCancelCause(suite.Context())
+	timer := time.AfterFunc(maxDuration-reserve, func() {
+		suite.Logger().Warnf("Stopping the run to clean up before it exceeds its maximum duration of %s", maxDuration)
+		cancel(stormerror.NewDeadlineError(maxDuration))
+	})
+
+	deadline := time.Now().Add(maxDuration)
+	guard.ctx = ctx
+	guards.cleanup = guard.withDeadline(deadline).forCleanups()
+	guards.testCase = guard.withDeadline(deadline.Add(-reserve / 2))
+
+	release := func() {
+		timer.Stop()
+		cancel(nil)
+	}
+
+	return &limitedSuite{SuiteContext: suite, ctx: ctx}, guards, release, nil
+}
@@ -0,0 +1,45 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/microsoft/storm/pkg/storm/core"
+)
+
+type timeLimitedScenario struct {
+	core.BaseScenario
+}
+
+func (s *timeLimitedScenario) Name() string { return "limited" }
+
+func (s *timeLimitedScenario) RegisterTestCases(core.TestRegistrar) error { return nil }
+
+func (s *timeLimitedScenario) MaxDuration() time.Duration { return time.Hour }
+
+func TestTimeLimitOptions(t *testing.T) {
+	disabled := time.Duration(0)
+	opts := TimeLimitOptions{GracePeriod: 30 * time.Second}
+
+	if d := opts.maxDuration(&timeLimitedScenario{}); d != time.Hour {
+		t.Errorf("expected the scenario's own limit, got %s", d)
+	}
+
+	opts.MaxDuration = &disabled
+	if d := opts.maxDuration(&timeLimitedScenario{}); d != 0 {
+		t.Errorf("expected the flag to disable the limit, got %s", d)
+	}
+
+	if r, _ := opts.cleanupReserve(time.Hour); r != 30*time.Second {
+		t.Errorf("expected the grace period to be reserved, got %s", r)
+	}
+
+	if r, _ := opts.cleanupReserve(time.Minute / 2); r != 15*time.Second {
+		t.Errorf("expected half of the maximum duration to be reserved, got %s", r)
+	}
+
+	opts.CleanupReserve = time.Minute
+	if _, err := opts.cleanupReserve(time.Minute); err == nil {
+		t.Errorf("expected a reserve as long as the maximum duration to be rejected")
+	}
+}
@@ -0,0 +1,221 @@
+package slogcapture
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/microsoft/storm/pkg/storm/results"
+)
+
+// The sink of the output capture currently running, if any.
+var (
+	activeSink      func(results.OutputLine)
+	activeSinkMutex sync.RWMutex
+)
+
+// SetActiveSink sets the sink that handlers created with ActiveSink record
+// into, and returns a function that restores the previous one. Output capture
+// is process-wide, so there is at most one active sink at a time.
+func SetActiveSink(sink func(results.OutputLine)) (restore func()) {
+	activeSinkMutex.Lock()
+	previous := activeSink
+	activeSink = sink
+	activeSinkMutex.Unlock()
+
+	return func() {
+		activeSinkMutex.Lock()
+		activeSink = previous
+		activeSinkMutex.Unlock()
+	}
+}
+
+// ActiveSink returns the sink of the output capture currently running, or nil
+// if there is none.
+func ActiveSink() func(results.OutputLine) {
+	activeSinkMutex.RLock()
+	defer activeSinkMutex.RUnlock()
+	return activeSink
+}
+
+// Handler is a slog.Handler that records log records as captured output lines,
+// keeping their attributes. When there is nowhere to record into, records are
+// passed to a fallback handler instead.
+type Handler struct {
+	// Returns where to record into, or nil to use the fallback.
+	sink     func() func(results.OutputLine)
+	fallback slog.Handler
+	attrs    []slog.Attr
+	groups   []string
+}
+
+// NewHandler creates a handler recording into the sink returned by the given
+// function at the time of each record, or into the fallback handler when it
+// returns nil.
+func NewHandler(sink func() func(results.OutputLine), fallback slog.Handler) *Handler {
+	return &Handler{
+		sink:     sink,
+		fallback: fallback,
+	}
+}
+
+// Enabled implements slog.Handler. Every level is captured, filtering by level
+// is left to whoever displays the output.
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	if h.sink() != nil {
+		return true
+	}
+
+	return h.fallback.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler.
+func (h *Handler) Handle(ctx context.Context, record slog.Record) error {
+	sink := h.sink()
+	if sink == nil {
+		return h.fallback.Handle(ctx, record)
+	}
+
+	attrs := make([]slog.Attr, 0, len(h.attrs)+record.NumAttrs())
+	attrs = append(attrs, h.attrs...)
+	prefix := groupPrefix(h.groups)
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, slog.Attr{Key: prefix + attr.Key, Value: attr.Value})
+		return true
+	})
+
+	line := results.OutputLine{
+		Time:   record.Time,
+		Stream: results.OutputStreamSlog,
+		Level:  LevelName(record.Level),
+		Attrs:  make(map[string]any),
+	}
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "%-5s %s", record.Level.String(), record.Message)
+	for _, attr := range flatten(attrs) {
+		fmt.Fprintf(&text, " %s=%s", attr.Key, quote(attr.Value.String()))
+		line.Attrs[attr.Key] = AttrValue(attr.Value.Any())
+	}
+
+	if len(line.Attrs) == 0 {
+		line.Attrs = nil
+	}
+
+	if line.Time.IsZero() {
+		line.Time = time.Now()
+	}
+
+	for _, text := range strings.Split(text.String(), "\n") {
+		line.Text = text
+		sink(line)
+	}
+
+	return nil
+}
+
+// WithAttrs implements slog.Handler.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	prefix := groupPrefix(h.groups)
+	clone := *h
+	clone.fallback = h.fallback.WithAttrs(attrs)
+	clone.attrs = append([]slog.Attr(nil), h.attrs...)
+	for _, attr := range attrs {
+		clone.attrs = append(clone.attrs, slog.Attr{Key: prefix + attr.Key, Value: attr.Value})
+	}
+
+	return &clone
+}
+
+// WithGroup implements slog.Handler.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	clone := *h
+	clone.fallback = h.fallback.WithGroup(name)
+	clone.groups = append(append([]string(nil), h.groups...), name)
+	return &clone
+}
+
+// LevelName returns the name of the logrus level matching the given slog
+// level, so that lines of both loggers are filtered alike.
+func LevelName(level slog.Level) string {
+	switch {
+	case level < slog.LevelInfo:
+		return "debug"
+	case level < slog.LevelWarn:
+		return "info"
+	case level < slog.LevelError:
+		return "warning"
+	default:
+		return "error"
+	}
+}
+
+// AttrValue converts the value of a log attribute to a value that can always
+// be serialized to JSON: booleans, numbers and strings are kept as they are,
+// anything else is formatted as a string.
+func AttrValue(value any) any {
+	switch v := value.(type) {
+	case nil, bool, string,
+		int, int8, int16, int32, int64,
+		uint, uint8, uint16, uint32, uint64,
+		float32, float64:
+		return v
+	case error:
+		return v.Error()
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// flatten resolves the given attributes, expanding groups into attributes
+// with dotted keys and dropping empty ones.
+func flatten(attrs []slog.Attr) []slog.Attr {
+	flat := make([]slog.Attr, 0, len(attrs))
+	for _, attr := range attrs {
+		attr.Value = attr.Value.Resolve()
+		if attr.Value.Kind() != slog.KindGroup {
+			if attr.Key != "" {
+				flat = append(flat, attr)
+			}
+			continue
+		}
+
+		prefix := ""
+		if attr.Key != "" {
+			prefix = attr.Key + "."
+		}
+
+		for _, member := range flatten(attr.Value.Group()) {
+			flat = append(flat, slog.Attr{Key: prefix + member.Key, Value: member.Value})
+		}
+	}
+
+	return flat
+}
+
+func groupPrefix(groups []string) string {
+	if len(groups) == 0 {
+		return ""
+	}
+
+	return strings.Join(groups, ".") + "."
+}
+
+// quote quotes a value if it would be ambiguous in a key=value list.
+func quote(s string) string {
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		return strconv.Quote(s)
+	}
+
+	return s
+}
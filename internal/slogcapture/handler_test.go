@@ -0,0 +1,41 @@
+package slogcapture
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/microsoft/storm/pkg/storm/results"
+)
+
+func TestHandlerRecordsAttributes(t *testing.T) {
+	var lines []results.OutputLine
+	sink := func(line results.OutputLine) { lines = append(lines, line) }
+	handler := NewHandler(
+		func() func(results.OutputLine) { return sink },
+		slog.NewTextHandler(io.Discard, nil),
+	)
+
+	slog.New(handler).With("a", 1).WithGroup("g").Warn("hello", "b", "two words", slog.Group("c", "d", true))
+
+	if len(lines) != 1 {
+		t.Fatalf("expected 1 line, got %d", len(lines))
+	}
+
+	line := lines[0]
+	if line.Stream != results.OutputStreamSlog || line.Level != "warning" {
+		t.Errorf("expected a slog warning line, got %s:%s", line.Stream, line.Level)
+	}
+
+	expectedText := `WARN  hello a=1 g.b="two words" g.c.d=true`
+	if line.Text != expectedText {
+		t.Errorf("expected text %q, got %q", expectedText, line.Text)
+	}
+
+	expectedAttrs := map[string]any{"a": int64(1), "g.b": "two words", "g.c.d": true}
+	for key, value := range expectedAttrs {
+		if line.Attrs[key] != value {
+			t.Errorf("expected attribute %s=%v, got %v", key, value, line.Attrs[key])
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package slogcapture
+
+import (
+	"log/slog"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LogrusLeveler is a slog.Leveler following the level of a logrus logger, so
+// that slog loggers honor the verbosity of the suite.
+type LogrusLeveler struct {
+	Logger *logrus.Logger
+}
+
+// Level implements slog.Leveler.
+func (l LogrusLeveler) Level() slog.Level {
+	switch level := l.Logger.GetLevel(); {
+	case level >= logrus.TraceLevel:
+		return slog.LevelDebug - 4
+	case level == logrus.DebugLevel:
+		return slog.LevelDebug
+	case level == logrus.InfoLevel:
+		return slog.LevelInfo
+	case level == logrus.WarnLevel:
+		return slog.LevelWarn
+	default:
+		return slog.LevelError
+	}
+}
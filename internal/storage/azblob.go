@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+)
+
+// Version of the Blob service REST API used for requests.
+const azureBlobAPIVersion = "2021-08-06"
+
+// AzureBlob uploads artifacts as block blobs to a container of Azure Blob
+// Storage or a compatible service, authenticating with a shared access
+// signature.
+type AzureBlob struct {
+	endpoint  *url.URL
+	container string
+	prefix    string
+	sasToken  string
+	client    *http.Client
+}
+
+// NewAzureBlob creates a storage uploading artifacts to the given container
+// of the storage account at the given endpoint, under the given prefix. The
+// SAS token, if any, is appended to every request but never to the URLs
+// recorded in results.
+func NewAzureBlob(endpoint *url.URL, container string, prefix string, sasToken string) *AzureBlob {
+	return &AzureBlob{
+		endpoint:  endpoint,
+		container: container,
+		prefix:    prefix,
+		sasToken:  strings.TrimPrefix(sasToken, "?"),
+		client:    &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// newAzureBlobFromURL creates an Azure Blob storage from an
+// azblob://account/container/prefix URL. The endpoint of the account can be
+// given as a query parameter, e.g. for a local emulator, and defaults to the
+// public Azure endpoint. The SAS token is read from the
+// AZURE_STORAGE_SAS_TOKEN environment variable.
+func newAzureBlobFromURL(u *url.URL) (*AzureBlob, error) {
+	container, prefix, _ := strings.Cut(strings.TrimPrefix(u.Path, "/"), "/")
+	if u.Host == "" || container == "" {
+		return nil, fmt.Errorf("artifact storage URL '%s' must name an account and a container", u.Redacted())
+	}
+
+	endpoint := u.Query().Get("endpoint")
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.blob.core.windows.net", u.Host)
+	}
+
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil || endpointURL.Host == "" {
+		return nil, fmt.Errorf("invalid Azure Blob endpoint '%s'", endpoint)
+	}
+
+	return NewAzureBlob(endpointURL, container, prefix, os.Getenv("AZURE_STORAGE_SAS_TOKEN")), nil
+}
+
+func (a *AzureBlob) Upload(ctx context.Context, key string, filePath string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", filePath, err)
+	}
+
+	target := a.URL(key)
+	if a.sasToken != "" {
+		target += "?" + a.sasToken
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, target, file)
+	if err != nil {
+		return err
+	}
+
+	req.ContentLength = info.Size()
+	req.Header.Set("x-ms-blob-type", "BlockBlob")
+	req.Header.Set("x-ms-version", azureBlobAPIVersion)
+	if contentType := mime.TypeByExtension(path.Ext(key)); contentType != "" {
+		req.Header.Set("x-ms-blob-content-type", contentType)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		// Keep the SAS token out of errors, which end up in results.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = a.URL(key)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	return checkResponse(resp)
+}
+
+func (a *AzureBlob) URL(key string) string {
+	return strings.TrimSuffix(a.endpoint.String(), "/") + "/" + url.PathEscape(a.container) + "/" + escapeKey(joinKey(a.prefix, key), url.PathEscape)
+}
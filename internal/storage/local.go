@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// Local copies artifacts to a local directory.
+type Local struct {
+	root string
+}
+
+// NewLocal creates a storage copying artifacts to the given directory, which
+// is created as needed.
+func NewLocal(root string) *Local {
+	abs, err := filepath.Abs(root)
+	if err == nil {
+		root = abs
+	}
+
+	return &Local{root: root}
+}
+
+func (l *Local) Upload(ctx context.Context, key string, path string) error {
+	dest := filepath.Join(l.root, filepath.FromSlash(key))
+	err := os.MkdirAll(filepath.Dir(dest), 0o755)
+	if err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", dest, err)
+	}
+
+	input, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer input.Close()
+
+	output, err := os.Create(dest)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", dest, err)
+	}
+
+	_, err = io.Copy(output, input)
+	closeErr := output.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to copy %s to %s: %w", path, dest, err)
+	}
+
+	return nil
+}
+
+func (l *Local) URL(key string) string {
+	location := url.URL{
+		Scheme: "file",
+		Path:   filepath.ToSlash(filepath.Join(l.root, filepath.FromSlash(key))),
+	}
+
+	return location.String()
+}
@@ -0,0 +1,208 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Payload hash sent instead of the hash of the body, so that files can be
+// streamed without being read twice.
+const s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+
+// S3Credentials are the credentials used to sign requests to an S3-compatible
+// object store.
+type S3Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// S3 uploads artifacts to a bucket of an S3-compatible object store, using
+// path-style URLs and AWS Signature Version 4.
+type S3 struct {
+	endpoint    *url.URL
+	region      string
+	bucket      string
+	prefix      string
+	credentials S3Credentials
+	client      *http.Client
+}
+
+// NewS3 creates a storage uploading artifacts to the given bucket, under the
+// given prefix. Requests are sent to the given endpoint and signed for the
+// given region. Requests are not signed when no access key is given.
+func NewS3(endpoint *url.URL, region string, bucket string, prefix string, credentials S3Credentials) *S3 {
+	return &S3{
+		endpoint:    endpoint,
+		region:      region,
+		bucket:      bucket,
+		prefix:      prefix,
+		credentials: credentials,
+		client:      &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// newS3FromURL creates an S3 storage from a s3://bucket/prefix URL. The
+// region and endpoint can be given as query parameters, and default to the
+// AWS_REGION environment variable and the AWS endpoint of the region.
+// Credentials are read from the standard AWS environment variables.
+func newS3FromURL(u *url.URL) (*S3, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("missing bucket in artifact storage URL '%s'", u.Redacted())
+	}
+
+	region := u.Query().Get("region")
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	endpoint := u.Query().Get("endpoint")
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", region)
+	}
+
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil || endpointURL.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint '%s'", endpoint)
+	}
+
+	credentials := S3Credentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+
+	return NewS3(endpointURL, region, u.Host, u.Path, credentials), nil
+}
+
+func (s *S3) Upload(ctx context.Context, key string, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.URL(key), file)
+	if err != nil {
+		return err
+	}
+
+	req.ContentLength = info.Size()
+	s.sign(req, time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return checkResponse(resp)
+}
+
+func (s *S3) URL(key string) string {
+	return strings.TrimSuffix(s.endpoint.String(), "/") + "/" + s3Escape(s.bucket) + "/" + escapeKey(joinKey(s.prefix, key), s3Escape)
+}
+
+// s3Escape escapes every byte but unreserved characters, as expected in the
+// canonical requests of AWS Signature Version 4.
+func s3Escape(segment string) string {
+	var escaped strings.Builder
+	for _, c := range []byte(segment) {
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("-._~", c) >= 0 {
+			escaped.WriteByte(c)
+		} else {
+			fmt.Fprintf(&escaped, "%%%02X", c)
+		}
+	}
+
+	return escaped.String()
+}
+
+// sign signs the given request with AWS Signature Version 4, as of the given
+// time.
+func (s *S3) sign(req *http.Request, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", s3UnsignedPayload)
+	if s.credentials.SessionToken != "" {
+		req.Header.Set("x-amz-security-token", s.credentials.SessionToken)
+	}
+
+	if s.credentials.AccessKeyID == "" {
+		return
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name := range req.Header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "x-amz-") || lower == "content-type" {
+			headers[lower] = strings.TrimSpace(req.Header.Get(name))
+		}
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", name, headers[name])
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		s3UnsignedPayload,
+	}, "\n")
+
+	date := amzDate[:8]
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", date, s.region)
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(hash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.credentials.SecretAccessKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.credentials.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
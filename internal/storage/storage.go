@@ -0,0 +1,93 @@
+// Package storage implements the artifact storages selected with the
+// --artifact-storage flag.
+package storage
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/microsoft/storm/pkg/storm/artifacts"
+)
+
+// Timeout of a single upload request. Large artifacts are expected to be
+// bundled or limited in size.
+const requestTimeout = 10 * time.Minute
+
+// Parse creates the storage described by the given URL:
+//
+//   - A local path or file:///path copies artifacts to a directory, such as a
+//     network share.
+//   - s3://bucket/prefix uploads artifacts to an S3-compatible object store.
+//   - azblob://account/container/prefix uploads artifacts to Azure Blob
+//     Storage or a compatible service.
+//
+// For object stores, the endpoint can be set with an endpoint query
+// parameter, and credentials are read from the environment. An empty string
+// returns a nil storage.
+func Parse(spec string) (artifacts.Storage, error) {
+	if spec == "" {
+		return nil, nil
+	}
+
+	if !strings.Contains(spec, "://") {
+		return NewLocal(spec), nil
+	}
+
+	u, err := url.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid artifact storage URL '%s': %w", spec, err)
+	}
+
+	switch u.Scheme {
+	case "file":
+		return NewLocal(u.Path), nil
+	case "s3":
+		return newS3FromURL(u)
+	case "azblob":
+		return newAzureBlobFromURL(u)
+	default:
+		return nil, fmt.Errorf("unsupported artifact storage '%s', expected a path, file://, s3:// or azblob:// URL", spec)
+	}
+}
+
+// joinKey joins the given prefix and key with a slash, ignoring an empty
+// prefix.
+func joinKey(prefix string, key string) string {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return key
+	}
+
+	return prefix + "/" + key
+}
+
+// escapeKey escapes every segment of a slash-separated key with the given
+// function, for use in a URL path.
+func escapeKey(key string, escape func(string) string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = escape(segment)
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// checkResponse returns an error describing a response with a non-2xx status
+// code, including the beginning of its body.
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	body := make([]byte, 512)
+	n, _ := resp.Body.Read(body)
+	message := strings.TrimSpace(string(body[:n]))
+	if message == "" {
+		return fmt.Errorf("upload failed with status %s", resp.Status)
+	}
+
+	return fmt.Errorf("upload failed with status %s: %s", resp.Status, message)
+}
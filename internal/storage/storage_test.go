@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// standIn is a minimal object store recording the objects uploaded to it.
+type standIn struct {
+	mutex    sync.Mutex
+	objects  map[string]string
+	requests []*http.Request
+}
+
+func newStandIn(t *testing.T) (*standIn, *httptest.Server) {
+	s := &standIn{objects: make(map[string]string)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		s.objects[r.URL.EscapedPath()] = string(body)
+		s.requests = append(s.requests, r)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	t.Cleanup(server.Close)
+
+	return s, server
+}
+
+func writeFile(t *testing.T, contents string) string {
+	path := filepath.Join(t.TempDir(), "artifact.log")
+	err := os.WriteFile(path, []byte(contents), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestS3(t *testing.T) {
+	objects, server := newStandIn(t)
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+
+	storage, err := Parse("s3://bucket/runs?region=eu-west-1&endpoint=" + server.URL)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	err = storage.Upload(context.Background(), "test case/out+1.log", writeFile(t, "hello"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	key := "/bucket/runs/test%20case/out%2B1.log"
+	if objects.objects[key] != "hello" {
+		t.Errorf("expected object %s to be uploaded, got %v", key, objects.objects)
+	}
+
+	if url := storage.URL("test case/out+1.log"); url != server.URL+key {
+		t.Errorf("expected URL %s, got %s", server.URL+key, url)
+	}
+
+	auth := objects.requests[0].Header.Get("Authorization")
+	prefix := "AWS4-HMAC-SHA256 Credential=AKID/"
+	if !strings.HasPrefix(auth, prefix) || !strings.Contains(auth, "/eu-west-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=") {
+		t.Errorf("expected a signed request, got Authorization '%s'", auth)
+	}
+}
+
+func TestAzureBlob(t *testing.T) {
+	objects, server := newStandIn(t)
+	t.Setenv("AZURE_STORAGE_SAS_TOKEN", "?sv=2021&sig=secret")
+
+	storage, err := Parse("azblob://account/container/runs?endpoint=" + server.URL + "/account")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	err = storage.Upload(context.Background(), "test/out.log", writeFile(t, "hello"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	key := "/account/container/runs/test/out.log"
+	if objects.objects[key] != "hello" {
+		t.Errorf("expected object %s to be uploaded, got %v", key, objects.objects)
+	}
+
+	req := objects.requests[0]
+	if req.URL.Query().Get("sig") != "secret" || req.Header.Get("x-ms-blob-type") != "BlockBlob" {
+		t.Errorf("expected a block blob upload with the SAS token, got %s %v", req.URL, req.Header)
+	}
+
+	if url := storage.URL("test/out.log"); url != server.URL+key {
+		t.Errorf("expected URL %s without the SAS token, got %s", server.URL+key, url)
+	}
+}
+
+func TestUploadFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "AccessDenied", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	storage, err := Parse("azblob://account/container?endpoint=" + server.URL)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	err = storage.Upload(context.Background(), "out.log", writeFile(t, "hello"))
+	if err == nil || !strings.Contains(err.Error(), "403") || !strings.Contains(err.Error(), "AccessDenied") {
+		t.Errorf("expected a 403 error with the response body, got %v", err)
+	}
+}
+
+func TestLocal(t *testing.T) {
+	root := t.TempDir()
+	storage, err := Parse("file://" + root)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	err = storage.Upload(context.Background(), "test/out.log", writeFile(t, "hello"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(root, "test", "out.log"))
+	if err != nil || string(data) != "hello" {
+		t.Errorf("expected the file to be copied, got '%s' (%v)", data, err)
+	}
+
+	if url := storage.URL("test/out.log"); url != "file://"+root+"/test/out.log" {
+		t.Errorf("unexpected URL %s", url)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, spec := range []string{"s3://", "azblob://account", "ftp://host/path"} {
+		_, err := Parse(spec)
+		if err == nil {
+			t.Errorf("expected an error for '%s'", spec)
+		}
+	}
+}
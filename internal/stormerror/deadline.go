@@ -0,0 +1,22 @@
+package stormerror
+
+import (
+	"fmt"
+	"time"
+)
+
+// DeadlineError is the cause of the cancellation of the suite context when a
+// run exceeds its maximum duration.
+type DeadlineError struct {
+	MaxDuration time.Duration
+}
+
+func NewDeadlineError(maxDuration time.Duration) *DeadlineError {
+	return &DeadlineError{
+		MaxDuration: maxDuration,
+	}
+}
+
+func (de *DeadlineError) Error() string {
+	return fmt.Sprintf("exceeded the maximum duration of %s", de.MaxDuration)
+}
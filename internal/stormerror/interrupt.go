@@ -0,0 +1,33 @@
+package stormerror
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// InterruptedError is the cause of the cancellation of the suite context when
+// the suite receives a termination signal.
+type InterruptedError struct {
+	Signal os.Signal
+}
+
+func NewInterruptedError(signal os.Signal) *InterruptedError {
+	return &InterruptedError{
+		Signal: signal,
+	}
+}
+
+func (ie *InterruptedError) Error() string {
+	return fmt.Sprintf("interrupted by signal '%s'", ie.Signal)
+}
+
+// ExitCode returns the exit code of a process terminated by the signal,
+// following the shell convention of 128 plus the signal number.
+func (ie *InterruptedError) ExitCode() int {
+	if sig, ok := ie.Signal.(syscall.Signal); ok {
+		return 128 + int(sig)
+	}
+
+	return 128 + int(syscall.SIGINT)
+}
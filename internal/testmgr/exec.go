@@ -0,0 +1,170 @@
+package testmgr
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/microsoft/storm/pkg/storm/core"
+	"github.com/microsoft/storm/pkg/storm/results"
+)
+
+// How long to wait for the output of a command to be closed after it exits or
+// is killed, e.g. when it leaves behind children that hold on to its pipes.
+const execWaitDelay = 5 * time.Second
+
+// Exec implements core.TestCase.
+func (t *TestCase) Exec(name string, args ...string) (core.ExecResult, error) {
+	return t.ExecWithOptions(core.ExecOptions{}, name, args...)
+}
+
+// ExecWithOptions implements core.TestCase.
+func (t *TestCase) ExecWithOptions(opts core.ExecOptions, name string, args ...string) (core.ExecResult, error) {
+	commandLine := strings.Join(append([]string{name}, args...), " ")
+	prefix := opts.Prefix
+	if prefix == "" {
+		prefix = filepath.Base(name)
+	}
+
+	transcript := &execTranscript{}
+	var record = func(stream results.OutputStream, text string) {
+		transcript.add(stream, text)
+		t.writeOutput(stream, fmt.Sprintf("[%s] %s", prefix, text))
+	}
+
+	var stdout, stderr bytes.Buffer
+	stdoutWriter := newLineWriter(&stdout, func(text string) { record(results.OutputStreamStdout, text) })
+	stderrWriter := newLineWriter(&stderr, func(text string) { record(results.OutputStreamStderr, text) })
+
+	cmd := exec.CommandContext(t.ctx, name, args...)
+	cmd.Dir = opts.Dir
+	cmd.Env = opts.Env
+	cmd.Stdin = opts.Stdin
+	cmd.Stdout = stdoutWriter
+	cmd.Stderr = stderrWriter
+	cmd.WaitDelay = execWaitDelay
+
+	record(results.OutputStreamStdout, "$ "+commandLine)
+
+	start := time.Now()
+	err := cmd.Run()
+	duration := time.Since(start)
+
+	stdoutWriter.flush()
+	stderrWriter.flush()
+
+	result := core.ExecResult{
+		Command:  commandLine,
+		ExitCode: -1,
+		Duration: duration,
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+	}
+
+	if cmd.ProcessState != nil {
+		result.ExitCode = cmd.ProcessState.ExitCode()
+	}
+
+	// Report cancellation rather than the signal that killed the command.
+	if err != nil && t.ctx.Err() != nil && !errors.Is(err, exec.ErrWaitDelay) {
+		err = fmt.Errorf("command '%s' was cancelled: %w", commandLine, context.Cause(t.ctx))
+	}
+
+	record(results.OutputStreamStdout, fmt.Sprintf("exited with code %d after %s", result.ExitCode, duration.Round(time.Millisecond)))
+
+	if opts.Transcript != "" {
+		t.publishTranscript(opts.Transcript, transcript)
+	}
+
+	return result, err
+}
+
+// Publishes the transcript of a command as a log file artifact.
+func (t *TestCase) publishTranscript(name string, transcript *execTranscript) {
+	file, err := os.CreateTemp("", "storm-exec-*.log")
+	if err != nil {
+		t.Error(fmt.Errorf("failed to create transcript file for %s: %w", name, err))
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.Write(transcript.bytes())
+	file.Close()
+	if err != nil {
+		t.Error(fmt.Errorf("failed to write transcript file for %s: %w", name, err))
+	}
+
+	t.broker.PublishLogFile(name, file.Name())
+}
+
+// execTranscript keeps every line of output of a command, in the order they
+// were received, tagged with the time and stream they came from.
+type execTranscript struct {
+	mutex sync.Mutex
+	buf   bytes.Buffer
+}
+
+func (tr *execTranscript) add(stream results.OutputStream, text string) {
+	tr.mutex.Lock()
+	defer tr.mutex.Unlock()
+	fmt.Fprintf(&tr.buf, "%s [%s] %s\n", time.Now().Format(time.RFC3339Nano), stream, text)
+}
+
+func (tr *execTranscript) bytes() []byte {
+	tr.mutex.Lock()
+	defer tr.mutex.Unlock()
+	return tr.buf.Bytes()
+}
+
+// lineWriter is an io.Writer that keeps a copy of everything written to it and
+// calls a function for every complete line. Lines longer than
+// results.MaxOutputLineLength are split.
+type lineWriter struct {
+	copy    *bytes.Buffer
+	pending []byte
+	line    func(string)
+}
+
+func newLineWriter(copy *bytes.Buffer, line func(string)) *lineWriter {
+	return &lineWriter{
+		copy: copy,
+		line: line,
+	}
+}
+
+// Write implements io.Writer.
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.copy.Write(p)
+	w.pending = append(w.pending, p...)
+	for {
+		i := bytes.IndexByte(w.pending, '\n')
+		if i < 0 {
+			if len(w.pending) >= results.MaxOutputLineLength {
+				w.line(string(w.pending[:results.MaxOutputLineLength]))
+				w.pending = w.pending[results.MaxOutputLineLength:]
+				continue
+			}
+
+			break
+		}
+
+		w.line(strings.TrimRight(string(w.pending[:i]), "\r"))
+		w.pending = w.pending[i+1:]
+	}
+
+	return len(p), nil
+}
+
+// flush emits the last line if it was not terminated by a newline.
+func (w *lineWriter) flush() {
+	if len(w.pending) > 0 {
+		w.line(string(w.pending))
+		w.pending = nil
+	}
+}
@@ -0,0 +1,102 @@
+package testmgr
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/microsoft/storm/internal/slogcapture"
+	"github.com/microsoft/storm/pkg/storm/results"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LogrusCaptureHook records every entry logged through a logrus logger as
+// captured output lines tagged with the entry's level.
+type LogrusCaptureHook struct {
+	formatter logrus.Formatter
+	record    func(results.OutputLine)
+}
+
+// NewLogrusCaptureHook creates a hook that formats entries with the given
+// formatter and passes every resulting line to record.
+func NewLogrusCaptureHook(formatter logrus.Formatter, record func(results.OutputLine)) *LogrusCaptureHook {
+	return &LogrusCaptureHook{
+		formatter: formatter,
+		record:    record,
+	}
+}
+
+// Levels implements logrus.Hook.
+func (h *LogrusCaptureHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire implements logrus.Hook.
+func (h *LogrusCaptureHook) Fire(entry *logrus.Entry) error {
+	data, err := h.formatter.Format(entry)
+	if err != nil {
+		return fmt.Errorf("failed to format captured log entry: %w", err)
+	}
+
+	var attrs map[string]any
+	if len(entry.Data) > 0 {
+		attrs = make(map[string]any, len(entry.Data))
+		for key, value := range entry.Data {
+			attrs[key] = slogcapture.AttrValue(value)
+		}
+	}
+
+	for _, text := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		h.record(results.OutputLine{
+			Time:   entry.Time,
+			Stream: results.OutputStreamLogrus,
+			Level:  entry.Level.String(),
+			Text:   text,
+			Attrs:  attrs,
+		})
+	}
+
+	return nil
+}
+
+// newTestCaseLogger creates a logger dedicated to the given test case. Every
+// entry, at any level, is recorded into the test case's captured output; the
+// logger never writes anywhere else. Filtering by level is left to whoever
+// displays the output.
+func newTestCaseLogger(t *TestCase, fields logrus.Fields) *logrus.Entry {
+	return NewCaptureLogger(t.recordOutput).WithFields(fields)
+}
+
+// NewCaptureLogger creates a logger passing every entry, at any level, to
+// record as captured output lines. The logger never writes anywhere else.
+func NewCaptureLogger(record func(results.OutputLine)) *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	logger.SetLevel(logrus.TraceLevel)
+	logger.AddHook(NewLogrusCaptureHook(
+		&logrus.TextFormatter{ForceColors: true},
+		record,
+	))
+
+	return logger
+}
+
+// newTestCaseSlogLogger creates a slog logger dedicated to the given test case,
+// recording every record into the test case's captured output.
+func newTestCaseSlogLogger(t *TestCase, fields logrus.Fields) *slog.Logger {
+	handler := slogcapture.NewHandler(
+		func() func(results.OutputLine) { return t.recordOutput },
+		slog.NewTextHandler(io.Discard, nil),
+	)
+
+	attrs := make([]any, 0, len(fields))
+	for _, key := range slices.Sorted(maps.Keys(fields)) {
+		attrs = append(attrs, slog.Any(key, fields[key]))
+	}
+
+	return slog.New(handler).With(attrs...)
+}
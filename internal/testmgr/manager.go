@@ -2,11 +2,18 @@ package testmgr
 
 import (
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/microsoft/storm/internal/artifacts"
+	"github.com/microsoft/storm/internal/checkpoint"
 	"github.com/microsoft/storm/internal/collector"
+	"github.com/microsoft/storm/internal/fixtures"
 	"github.com/microsoft/storm/pkg/storm/core"
+	stormfixtures "github.com/microsoft/storm/pkg/storm/fixtures"
+	"github.com/microsoft/storm/pkg/storm/results"
+
+	"github.com/sirupsen/logrus"
 )
 
 const (
@@ -17,9 +24,23 @@ const (
 type StormTestManager struct {
 	registrant core.TestRegistrantMetadata
 	suite      core.SuiteContext
+	args       []string
+	logDir     *string
+	artifacts  *artifacts.ArtifactManager
 	startTime  time.Time
-	endTime    time.Time
-	testCases  []*TestCase
+
+	// Brokers of the artifacts published outside of test cases.
+	setupBroker   *artifacts.ArtifactBroker
+	cleanupBroker *artifacts.ArtifactBroker
+	suiteBroker   *artifacts.ArtifactBroker
+	endTime       time.Time
+	testCases     []*TestCase
+
+	// Values of the scenario fixtures requested by the test cases.
+	fixtures *fixtures.Store
+
+	// Values shared between the test cases.
+	state *sharedState
 }
 
 func NewStormTestManager(
@@ -28,7 +49,9 @@ func NewStormTestManager(
 		core.TestRegistrant
 		core.TestRegistrantMetadata
 	},
+	args []string,
 	logDir *string,
+	artifactOpts artifacts.Options,
 ) (*StormTestManager, error) {
 	collected, err := collector.CollectTestCases(registrant)
 	if err != nil {
@@ -37,21 +60,172 @@ func NewStormTestManager(
 
 	// Create a global artifact manager. Each test case will attach itself to
 	// this manager when it is invoked.
-	artifactManager := artifacts.NewArtifactManager(suite, logDir)
+	artifactManager := artifacts.NewArtifactManager(suite, logDir, artifactOpts)
 
+	// Scenario fixtures are shared by all test cases of the run.
+	fixtureStore := fixtures.NewStore(stormfixtures.ScopeScenario, suite.Context(), logrus.NewEntry(suite.Logger()))
+
+	state := newSharedState()
 	testCases := make([]*TestCase, len(collected))
 	for i, testCase := range collected {
-		testCases[i] = newTestCase(testCase.Name, testCase.F, suite.Context(), artifactManager.NewBroker(), DEFAULT_TEST_CLEANUP_TIMEOUT)
+		fields := logrus.Fields{
+			"suite":      suite.Name(),
+			"registrant": registrant.Name(),
+			"test":       testCase.Name,
+		}
+		testCases[i] = newTestCase(testCase.Name, testCase.F, suite.Context(), registrant, fields, artifactManager.NewBroker(), DEFAULT_TEST_CLEANUP_TIMEOUT)
+		testCases[i].attachFixtures(suite.Fixtures(), suite.SuiteFixtures(), fixtureStore)
+		testCases[i].state = testCaseState{shared: state, testCase: testCases[i]}
 	}
+	state.testCases = testCases
 
 	return &StormTestManager{
 		registrant: registrant,
 		suite:      suite,
+		args:       args,
+		logDir:     logDir,
+		artifacts:  artifactManager,
 		startTime:  time.Now(),
 		testCases:  testCases,
+		fixtures:   fixtureStore,
+		state:      state,
+
+		setupBroker:   newDirectoryBroker(suite, artifactManager, artifacts.SetupDirName, registrant),
+		cleanupBroker: newDirectoryBroker(suite, artifactManager, artifacts.CleanupDirName, registrant),
+		suiteBroker:   newDirectoryBroker(suite, artifactManager, artifacts.SuiteDirName, registrant),
 	}, nil
 }
 
+// newDirectoryBroker creates a broker publishing artifacts to the given
+// directory of the log directory, outside of any test case.
+func newDirectoryBroker(suite core.SuiteContext, manager *artifacts.ArtifactManager, dir string, registrant core.TestRegistrantMetadata) *artifacts.ArtifactBroker {
+	broker := manager.NewBroker()
+	broker.Attach(artifacts.NewDirectoryOwner(dir, registrant, logrus.NewEntry(suite.Logger())))
+	return broker
+}
+
+// SetupArtifactBroker returns the broker of the artifacts published during the
+// registrant's setup, saved to the _setup directory.
+func (tm *StormTestManager) SetupArtifactBroker() *artifacts.ArtifactBroker {
+	return tm.setupBroker
+}
+
+// CleanupArtifactBroker returns the broker of the artifacts published during
+// the registrant's cleanup, saved to the _cleanup directory.
+func (tm *StormTestManager) CleanupArtifactBroker() *artifacts.ArtifactBroker {
+	return tm.cleanupBroker
+}
+
+// SuiteArtifactBroker returns the broker of the artifacts published through
+// the suite during the run, saved to the _suite directory.
+func (tm *StormTestManager) SuiteArtifactBroker() *artifacts.ArtifactBroker {
+	return tm.suiteBroker
+}
+
+// Artifacts returns the artifacts published outside of test cases: during the
+// setup, the cleanup and through the suite.
+func (tm *StormTestManager) Artifacts() []results.Artifact {
+	var published []results.Artifact
+	for _, broker := range []*artifacts.ArtifactBroker{tm.setupBroker, tm.suiteBroker, tm.cleanupBroker} {
+		published = append(published, broker.Published()...)
+	}
+
+	return published
+}
+
+// TeardownFixtures tears down the scenario fixtures requested by the test
+// cases.
+func (tm *StormTestManager) TeardownFixtures() error {
+	return tm.fixtures.Teardown()
+}
+
+// FixtureResults returns how the setup and teardown of the fixtures requested
+// during the run went: suite fixtures first, then scenario fixtures and the
+// test case fixtures of every test case.
+func (tm *StormTestManager) FixtureResults() []results.FixtureResult {
+	list := tm.suite.SuiteFixtures().Results()
+	list = append(list, tm.fixtures.Results()...)
+	for _, testCase := range tm.testCases {
+		list = append(list, testCase.FixtureResults()...)
+	}
+
+	return list
+}
+
+// Checkpoint returns the progress of the run so far, to be saved after each
+// test case.
+func (tm *StormTestManager) Checkpoint() checkpoint.Checkpoint {
+	testCases := make([]checkpoint.TestCase, len(tm.testCases))
+	for i, testCase := range tm.testCases {
+		testCases[i] = testCase.checkpoint()
+	}
+
+	return checkpoint.Checkpoint{
+		Suite:          tm.suite.Name(),
+		RegistrantType: tm.registrant.RegistrantType().String(),
+		Registrant:     tm.registrant.Name(),
+		Args:           tm.args,
+		TestCases:      testCases,
+		State:          tm.state.checkpoint(tm.suite.Logger()),
+	}
+}
+
+// Resume resumes the run saved to the given checkpoint: the test cases that
+// passed at the start of it are not run again, and the shared state saved with
+// them is restored. It returns how many test cases are skipped.
+func (tm *StormTestManager) Resume(saved *checkpoint.Checkpoint) (int, error) {
+	registrantType := tm.registrant.RegistrantType().String()
+	if saved.RegistrantType != registrantType || saved.Registrant != tm.registrant.Name() {
+		return 0, fmt.Errorf("checkpoint is for %s '%s', not %s '%s'", saved.RegistrantType, saved.Registrant, registrantType, tm.registrant.Name())
+	}
+
+	if !slices.Equal(saved.Args, tm.args) {
+		tm.suite.Logger().Warnf("Resuming a run that had other arguments: %q", saved.Args)
+	}
+
+	names := make([]string, len(tm.testCases))
+	for i, testCase := range tm.testCases {
+		names[i] = testCase.Name()
+	}
+
+	passed := saved.Passed(names)
+	for i := range passed {
+		tm.testCases[i].previous = &saved.TestCases[i]
+	}
+
+	tm.state.restore(saved.State)
+	return passed, nil
+}
+
+// Rerun restricts the run to the test cases with the given names, which did
+// not pass in an earlier run, and marks them as reruns. When dependencies is
+// set, the test cases before the last of them run as well, since test cases
+// may depend on the ones before them.
+func (tm *StormTestManager) Rerun(names []string, dependencies bool) {
+	last := -1
+	for i, testCase := range tm.testCases {
+		if slices.Contains(names, testCase.Name()) {
+			testCase.rerun = true
+			last = i
+		}
+	}
+
+	selected := make([]*TestCase, 0)
+	for i, testCase := range tm.testCases {
+		if testCase.rerun || dependencies && i < last {
+			selected = append(selected, testCase)
+		}
+	}
+
+	tm.testCases = selected
+	tm.state.testCases = selected
+}
+
+// StateResults dumps the values shared between the test cases.
+func (tm *StormTestManager) StateResults() []results.StateEntry {
+	return tm.state.results()
+}
+
 func (tm *StormTestManager) TestCases() []*TestCase {
 	return tm.testCases
 }
@@ -64,6 +238,27 @@ func (tm *StormTestManager) Suite() core.SuiteContext {
 	return tm.suite
 }
 
+// Returns the extra arguments the registrant was run with.
+func (tm *StormTestManager) Args() []string {
+	return tm.args
+}
+
+// Returns the directory logs and artifacts are saved to, or nil if there is
+// none.
+func (tm *StormTestManager) LogDir() *string {
+	return tm.logDir
+}
+
+// WaitArtifactUploads waits for the artifacts of all test cases to be uploaded
+// to the artifact storage, if any.
+func (tm *StormTestManager) WaitArtifactUploads() {
+	tm.artifacts.WaitUploads()
+}
+
+func (tm *StormTestManager) StartTime() time.Time {
+	return tm.startTime
+}
+
 func (tm *StormTestManager) StopTimer() {
 	tm.endTime = time.Now()
 }
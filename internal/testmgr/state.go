@@ -0,0 +1,186 @@
+package testmgr
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/microsoft/storm/internal/checkpoint"
+	"github.com/microsoft/storm/pkg/storm/results"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Longest value of the shared state kept in the results, in bytes.
+const maxStateValueLength = 1024
+
+// sharedState holds the values shared between the test cases of a run.
+type sharedState struct {
+	mutex     sync.Mutex
+	entries   map[string]stateEntry
+	keys      []string
+	testCases []*TestCase
+}
+
+type stateEntry struct {
+	value      any
+	producer   string
+	persistent bool
+}
+
+func newSharedState() *sharedState {
+	return &sharedState{
+		entries: make(map[string]stateEntry),
+	}
+}
+
+// results dumps the shared state, in the order the keys were first stored.
+func (s *sharedState) results() []results.StateEntry {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	dump := make([]results.StateEntry, len(s.keys))
+	for i, key := range s.keys {
+		entry := s.entries[key]
+		value := fmt.Sprintf("%+v", entry.value)
+		if raw, ok := entry.value.(json.RawMessage); ok {
+			value = string(raw)
+		}
+		if len(value) > maxStateValueLength {
+			value = value[:maxStateValueLength] + "..."
+		}
+
+		dump[i] = results.StateEntry{
+			Key:      key,
+			Producer: entry.producer,
+			Type:     fmt.Sprint(reflect.TypeOf(entry.value)),
+			Value:    value,
+		}
+	}
+
+	return dump
+}
+
+// checkpoint serializes the values of the persistent keys, in the order the
+// keys were first stored. Values that cannot be serialized are left out with a
+// warning.
+func (s *sharedState) checkpoint(log *logrus.Logger) []checkpoint.StateEntry {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var entries []checkpoint.StateEntry
+	for _, key := range s.keys {
+		entry := s.entries[key]
+		if !entry.persistent {
+			continue
+		}
+
+		value, err := json.Marshal(entry.value)
+		if err != nil {
+			log.Warnf("Not saving state '%s' to the checkpoint: %v", key, err)
+			continue
+		}
+
+		entries = append(entries, checkpoint.StateEntry{
+			Key:      key,
+			Producer: entry.producer,
+			Value:    value,
+		})
+	}
+
+	return entries
+}
+
+// restore stores the values saved to a checkpoint, as json.RawMessage values
+// decoded by state.Lookup.
+func (s *sharedState) restore(entries []checkpoint.StateEntry) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, entry := range entries {
+		if _, ok := s.entries[entry.Key]; !ok {
+			s.keys = append(s.keys, entry.Key)
+		}
+
+		s.entries[entry.Key] = stateEntry{value: entry.Value, producer: entry.Producer, persistent: true}
+	}
+}
+
+// testCaseState is the shared state as seen by a test case. It implements
+// state.Store.
+type testCaseState struct {
+	shared   *sharedState
+	testCase *TestCase
+}
+
+// Put implements state.Store.
+func (s testCaseState) Put(key string, value any, persistent bool) {
+	s.shared.mutex.Lock()
+	defer s.shared.mutex.Unlock()
+
+	if _, ok := s.shared.entries[key]; !ok {
+		s.shared.keys = append(s.shared.keys, key)
+	}
+
+	s.shared.entries[key] = stateEntry{value: value, producer: s.testCase.Name(), persistent: persistent}
+}
+
+// Lookup implements state.Store.
+func (s testCaseState) Lookup(key string) (any, bool) {
+	s.shared.mutex.Lock()
+	defer s.shared.mutex.Unlock()
+
+	entry, ok := s.shared.entries[key]
+	return entry.value, ok
+}
+
+// Missing implements state.Store.
+func (s testCaseState) Missing(key string, producer string) {
+	if producer == "" {
+		s.testCase.Error(fmt.Errorf("no previous test case stored state '%s'", key))
+	}
+
+	var producerCase *TestCase
+	producedBefore := false
+	for _, testCase := range s.shared.testCases {
+		if testCase == s.testCase {
+			producedBefore = producerCase != nil
+			break
+		}
+
+		if testCase.Name() == producer {
+			producerCase = testCase
+		}
+	}
+
+	if producedBefore && producerCase.Status() != TestCaseStatusPassed {
+		s.testCase.notRun(fmt.Sprintf("state '%s' is missing: test case '%s' did not pass (%s)", key, producer, producerCase.Status()))
+	}
+
+	switch {
+	case producedBefore && producerCase.PreviouslyPassed():
+		s.testCase.Error(fmt.Errorf("test case '%s' passed in the resumed run, but state '%s' was not saved to its checkpoint; make the key persistent", producer, key))
+	case producedBefore:
+		s.testCase.Error(fmt.Errorf("test case '%s' passed without storing state '%s'", producer, key))
+	case s.hasTestCase(producer):
+		s.testCase.Error(fmt.Errorf("state '%s' is stored by test case '%s', which runs after this one", key, producer))
+	default:
+		s.testCase.Error(fmt.Errorf("state '%s' is stored by test case '%s', which is not part of this run", key, producer))
+	}
+}
+
+// Error implements state.Store.
+func (s testCaseState) Error(err error) {
+	s.testCase.Error(err)
+}
+
+func (s testCaseState) hasTestCase(name string) bool {
+	for _, testCase := range s.shared.testCases {
+		if testCase.Name() == name {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,111 @@
+package testmgr
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/microsoft/storm/internal/checkpoint"
+	"github.com/microsoft/storm/pkg/storm/core"
+	"github.com/microsoft/storm/pkg/storm/state"
+
+	"github.com/sirupsen/logrus"
+)
+
+type testRegistrant struct{}
+
+func (testRegistrant) Name() string { return "registrant" }
+
+func (testRegistrant) RegistrantType() core.RegistrantType { return core.RegistrantTypeScenario }
+
+func newStateTestCases(names ...string) []*TestCase {
+	shared := newSharedState()
+	testCases := make([]*TestCase, len(names))
+	for i, name := range names {
+		testCases[i] = &TestCase{name: name, registrant: testRegistrant{}, status: TestCaseStatusPending, ctx: context.Background(), cancel: func() {}, cleanupTimeout: time.Second}
+		testCases[i].state = testCaseState{shared: shared, testCase: testCases[i]}
+	}
+	shared.testCases = testCases
+
+	return testCases
+}
+
+// runTestCase runs f as the body of the test case, which may stop it.
+func runTestCase(testCase *TestCase, f func()) {
+	testCase.status = TestCaseStatusRunning
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		f()
+	}()
+	wg.Wait()
+}
+
+func TestStateGetNamesProducer(t *testing.T) {
+	key := state.NewKey[int]("answer", "produce")
+	testCases := newStateTestCases("produce", "consume", "late")
+
+	runTestCase(testCases[0], func() { testCases[0].Fail("broken") })
+	runTestCase(testCases[1], func() { state.Get(testCases[1], key) })
+	if testCases[1].Status() != TestCaseStatusNotRun || !strings.Contains(testCases[1].Reason(), "'produce' did not pass") {
+		t.Errorf("expected the consumer not to run because of the producer, got %s: %s", testCases[1].Status(), testCases[1].Reason())
+	}
+
+	lateKey := state.NewKey[int]("answer", "late")
+	testCases = newStateTestCases("produce", "consume", "late")
+	runTestCase(testCases[1], func() { state.Get(testCases[1], lateKey) })
+	if testCases[1].Status() != TestCaseStatusError || !strings.Contains(testCases[1].Reason(), "runs after this one") {
+		t.Errorf("expected the consumer to error out, got %s: %s", testCases[1].Status(), testCases[1].Reason())
+	}
+
+	testCases = newStateTestCases("produce", "consume")
+	var got int
+	runTestCase(testCases[0], func() { state.Put(testCases[0], key, 42) })
+	runTestCase(testCases[1], func() { got = state.Get(testCases[1], key) })
+	if got != 42 {
+		t.Errorf("expected the stored value, got %d", got)
+	}
+
+	dump := testCases[0].state.shared.results()
+	if len(dump) != 1 || dump[0].Producer != "produce" || dump[0].Value != "42" || dump[0].Type != "int" {
+		t.Errorf("unexpected state dump %+v", dump)
+	}
+}
+
+func TestStateRestoredFromCheckpoint(t *testing.T) {
+	type vm struct{ Name string }
+	key := state.NewKey[vm]("vm", "produce").Persistent()
+	volatile := state.NewKey[int]("count", "produce")
+
+	testCases := newStateTestCases("produce", "consume")
+	runTestCase(testCases[0], func() {
+		state.Put(testCases[0], key, vm{Name: "vm1"})
+		state.Put(testCases[0], volatile, 1)
+	})
+	saved := testCases[0].state.shared.checkpoint(logrus.New())
+	if len(saved) != 1 || saved[0].Key != "vm" || string(saved[0].Value) != `{"Name":"vm1"}` {
+		t.Fatalf("expected only the persistent key to be saved, got %+v", saved)
+	}
+
+	testCases = newStateTestCases("produce", "consume")
+	testCases[0].state.shared.restore(saved)
+	testCases[0].previous = &checkpoint.TestCase{Name: "produce", Status: TestCaseStatusPassed}
+	testCases[0].MarkPreviouslyPassed()
+
+	var got vm
+	runTestCase(testCases[1], func() {
+		got = state.Get(testCases[1], key)
+		state.Get(testCases[1], volatile)
+	})
+	if got.Name != "vm1" {
+		t.Errorf("expected the restored value, got %+v", got)
+	}
+
+	if testCases[1].Status() != TestCaseStatusError || !strings.Contains(testCases[1].Reason(), "make the key persistent") {
+		t.Errorf("expected the consumer of a volatile key to error out, got %s: %s", testCases[1].Status(), testCases[1].Reason())
+	}
+}
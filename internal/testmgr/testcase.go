@@ -3,19 +3,31 @@ package testmgr
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"os"
+	"path"
 	"runtime"
 	"sync"
 	"time"
 
 	"github.com/microsoft/storm/internal/artifacts"
+	"github.com/microsoft/storm/internal/checkpoint"
+	"github.com/microsoft/storm/internal/fixtures"
+	"github.com/microsoft/storm/internal/stormerror"
 	stormartifacts "github.com/microsoft/storm/pkg/storm/artifacts"
 	"github.com/microsoft/storm/pkg/storm/core"
+	stormfixtures "github.com/microsoft/storm/pkg/storm/fixtures"
+	"github.com/microsoft/storm/pkg/storm/results"
+	"github.com/microsoft/storm/pkg/storm/state"
+
+	"github.com/sirupsen/logrus"
 )
 
 // TestCase represents a single test case within a test suite. It implements
 // the core.TestCase interface. This is the concrete implementation of a test
 // case that is managed by the StormTestManager.
 type TestCase struct {
+	parentCtx       context.Context
 	ctx             context.Context
 	cancel          context.CancelFunc
 	registrant      core.TestRegistrantMetadata
@@ -25,44 +37,76 @@ type TestCase struct {
 	status          TestCaseStatus
 	reason          string
 	err             error
-	collectedOutput []string
+	collectedOutput []results.OutputLine
+	outputSink      func(results.OutputLine)
+	outputOmitted   int
+	outputFile      string
+	logger          *logrus.Entry
+	slogLogger      *slog.Logger
 	f               core.TestCaseFunction
-	suiteCleanup    []func()
+	suiteCleanup    []func(context.Context)
 	waitGroup       sync.WaitGroup
 	broker          *artifacts.ArtifactBroker
 	cleanupTimeout  time.Duration
 	skipAllInvoked  bool
+	fixtures        *fixtures.Resolver
+	fixtureStore    *fixtures.Store
+	state           testCaseState
+	hookFailures    []results.HookFailure
+
+	// Status of the test case in the checkpoint the run resumes from, when it
+	// passed there.
+	previous *checkpoint.TestCase
+
+	// Whether the test case runs again because it did not pass in an earlier
+	// run.
+	rerun bool
 }
 
-// Internal constructor for a TestCase.
-func newTestCase(name string, f core.TestCaseFunction, ctx context.Context, artifactBroker *artifacts.ArtifactBroker, cleanupTimeout time.Duration) *TestCase {
+// Internal constructor for a TestCase. The test case's logger is populated
+// with the given fields.
+func newTestCase(name string, f core.TestCaseFunction, ctx context.Context, registrant core.TestRegistrantMetadata, logFields logrus.Fields, artifactBroker *artifacts.ArtifactBroker, cleanupTimeout time.Duration) *TestCase {
 	tc_ctx, cancel := context.WithCancel(ctx)
 	tc := &TestCase{
 		name:           name,
+		registrant:     registrant,
 		f:              f,
 		status:         TestCaseStatusPending,
 		cleanupTimeout: cleanupTimeout,
 		broker:         artifactBroker,
+		parentCtx:      ctx,
 		ctx:            tc_ctx,
 		cancel:         cancel,
 	}
 
+	tc.logger = newTestCaseLogger(tc, logFields)
+	tc.slogLogger = newTestCaseSlogLogger(tc, logFields)
+
 	// The test is attached to the broker so that it knows which test case it is
 	// publishing artifacts for.
-	artifactBroker.AttachTestCase(tc)
+	artifactBroker.Attach(tc)
 
 	return tc
 }
 
-// Executes the test case function. The returned error is guaranteed to be the
-// return of the test case function.
+// Marks the test case as running, before its BeforeEach hooks and its
+// function run.
+func (t *TestCase) Start() {
+	t.startTime = time.Now()
+	t.status = TestCaseStatusRunning
+}
+
+// Executes the test case function, starting the test case if it was not
+// already. The returned error is guaranteed to be the return of the test case
+// function.
 func (t *TestCase) Execute() error {
 	if t.f == nil {
 		panic(fmt.Sprintf("Test case '%s' has no runnable function", t.name))
 	}
 
-	t.startTime = time.Now()
-	t.status = TestCaseStatusRunning
+	if t.status == TestCaseStatusPending {
+		t.Start()
+	}
 
 	return t.f(t)
 }
@@ -137,7 +181,7 @@ func (t *TestCase) IsBailCondition() bool {
 }
 
 // Returns the collected output of the test case.
-func (t *TestCase) CollectedOutput() []string {
+func (t *TestCase) CollectedOutput() []results.OutputLine {
 	return t.collectedOutput
 }
 
@@ -157,10 +201,106 @@ func (t *TestCase) MarkNotRun(reason string) {
 	t.close(TestCaseStatusNotRun, reason, nil)
 }
 
-func (t *TestCase) SetCollectedOutput(val []string) {
+// Returns whether the test case passed in the checkpoint the run resumes
+// from, in which case it is not run again.
+func (t *TestCase) PreviouslyPassed() bool {
+	return t.previous != nil
+}
+
+// Returns whether the test case runs again because it did not pass in an
+// earlier run.
+func (t *TestCase) IsRerun() bool {
+	return t.rerun
+}
+
+// Marks a pending test case that previously passed as passed, with the
+// timings of the run it passed in.
+func (t *TestCase) MarkPreviouslyPassed() {
+	if t.status != TestCaseStatusPending || t.previous == nil {
+		panic(fmt.Sprintf("Test case '%s' cannot be marked as previously passed", t.name))
+	}
+
+	t.cancel()
+	t.status = TestCaseStatusPassed
+	t.reason = "previously passed"
+	t.startTime = t.previous.StartTime
+	endTime := t.previous.StartTime.Add(t.previous.Duration)
+	t.endTime = &endTime
+}
+
+// Returns the status of the test case to save to a checkpoint. A test case
+// that previously passed keeps its earlier status until it runs again.
+func (t *TestCase) checkpoint() checkpoint.TestCase {
+	if t.previous != nil && !t.status.Ran() {
+		return *t.previous
+	}
+
+	record := checkpoint.TestCase{
+		Name:   t.name,
+		Status: t.status,
+		Reason: t.reason,
+	}
+
+	if !t.startTime.IsZero() {
+		record.StartTime = t.startTime
+		record.Duration = t.RunTime()
+	}
+
+	return record
+}
+
+func (t *TestCase) SetCollectedOutput(val []results.OutputLine) {
 	t.collectedOutput = val
 }
 
+// Sets the file holding the full output of the test case, and the number of
+// lines of that output that were left out of the collected output.
+func (t *TestCase) SetOutputSpool(file string, omitted int) {
+	t.outputFile = file
+	t.outputOmitted = omitted
+}
+
+// Returns the path of the file holding the full output of the test case, or an
+// empty string if the output was not spooled.
+func (t *TestCase) OutputFile() string {
+	return t.outputFile
+}
+
+// Returns the number of lines left out of the collected output.
+func (t *TestCase) OutputOmitted() int {
+	return t.outputOmitted
+}
+
+// Sets the function used to record lines directly into the test case's
+// captured output, bypassing stdout and stderr. Pass nil to remove it.
+func (t *TestCase) SetOutputSink(sink func(results.OutputLine)) {
+	t.outputSink = sink
+}
+
+// Records a line of output for the test case. When no output sink is set, the
+// line is written to stdout, or stderr for any other stream, instead.
+func (t *TestCase) recordOutput(line results.OutputLine) {
+	if t.outputSink != nil {
+		t.outputSink(line)
+		return
+	}
+
+	if line.Stream == results.OutputStreamStdout {
+		fmt.Fprintln(os.Stdout, line.Text)
+	} else {
+		fmt.Fprintln(os.Stderr, line.Text)
+	}
+}
+
+// Records a line of text written to the given stream by the test case.
+func (t *TestCase) writeOutput(stream results.OutputStream, text string) {
+	t.recordOutput(results.OutputLine{
+		Time:   time.Now(),
+		Stream: stream,
+		Text:   text,
+	})
+}
+
 // Mark a test as errored. This is used when the test case panics or returns an
 // error.
 func (t *TestCase) MarkError(err error) {
@@ -177,8 +317,67 @@ func (t *TestCase) Pass() {
 	t.close(TestCaseStatusPassed, "", nil)
 }
 
+// Gives the test case the fixtures registered in the suite. Its test case
+// fixtures are kept in a store of its own, the others in the given stores.
+func (t *TestCase) attachFixtures(registered []*stormfixtures.Fixture, suiteStore stormfixtures.Instances, scenarioStore *fixtures.Store) {
+	t.fixtureStore = fixtures.NewStore(stormfixtures.ScopeTestCase, t.ctx, t.logger)
+	t.fixtures = fixtures.NewResolver(registered, map[stormfixtures.Scope]stormfixtures.Instances{
+		stormfixtures.ScopeSuite:    suiteStore,
+		stormfixtures.ScopeScenario: scenarioStore,
+		stormfixtures.ScopeTestCase: t.fixtureStore,
+	}, t)
+}
+
+// Tears down the test case fixtures set up for this test case.
+func (t *TestCase) TeardownFixtures() error {
+	return t.fixtureStore.Teardown()
+}
+
+// Returns the time the test case spent setting up fixtures.
+func (t *TestCase) FixtureSetupTime() time.Duration {
+	return t.fixtures.SetupTime()
+}
+
+// Returns how the setup and teardown of the test case fixtures of this test
+// case went.
+func (t *TestCase) FixtureResults() []results.FixtureResult {
+	return t.fixtureStore.Results()
+}
+
+// Gives the closed test case a new context, so that the code running after
+// it, such as its AfterEach hooks, can still run commands. When timeout is not
+// zero, the context is cancelled after it. The returned function cancels it.
+func (t *TestCase) RenewContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		t.ctx, t.cancel = context.WithTimeout(t.parentCtx, timeout)
+	} else {
+		t.ctx, t.cancel = context.WithCancel(t.parentCtx)
+	}
+
+	return t.ctx, t.cancel
+}
+
+// Records the failure of a hook run before or after the test case.
+func (t *TestCase) RecordHookFailure(hook string, err error) {
+	failure := results.HookFailure{
+		Hook:  hook,
+		Error: err.Error(),
+	}
+
+	if pe, ok := err.(stormerror.PanicError); ok {
+		failure.Stack = string(pe.Stack)
+	}
+
+	t.hookFailures = append(t.hookFailures, failure)
+}
+
+// Returns the failures of the hooks run before and after the test case.
+func (t *TestCase) HookFailures() []results.HookFailure {
+	return t.hookFailures
+}
+
 // Return the suite-level cleanup functions registered in this test case.
-func (t *TestCase) SuiteCleanupList() []func() {
+func (t *TestCase) SuiteCleanupList() []func(context.Context) {
 	return t.suiteCleanup
 }
 
@@ -186,6 +385,11 @@ func (t *TestCase) SuiteCleanupList() []func() {
 
 // SuiteCleanup implements core.TestCase.
 func (t *TestCase) SuiteCleanup(f func()) {
+	t.suiteCleanup = append(t.suiteCleanup, func(context.Context) { f() })
+}
+
+// SuiteCleanupWithContext implements core.TestCase.
+func (t *TestCase) SuiteCleanupWithContext(f func(ctx context.Context)) {
 	t.suiteCleanup = append(t.suiteCleanup, f)
 }
 
@@ -225,11 +429,24 @@ func (t *TestCase) SkipAll(reason string) {
 	runtime.Goexit()
 }
 
+// Closes the running test case as not run, because something it depends on
+// is missing.
+func (t *TestCase) notRun(reason string) {
+	t.close(TestCaseStatusNotRun, reason, nil)
+	runtime.Goexit()
+}
+
 // Name implements core.TestCase.
 func (t *TestCase) Name() string {
 	return t.name
 }
 
+// Returns the time at which the test case started running. This is the zero
+// time if the test case never started.
+func (t *TestCase) StartTime() time.Time {
+	return t.startTime
+}
+
 // RunTime implements core.TestCase. Returns the duration of the test case. If
 // the test case is still running, it returns the duration since the start time.
 func (t *TestCase) RunTime() time.Duration {
@@ -250,7 +467,43 @@ func (t *TestCase) BackgroundWaitGroup() *sync.WaitGroup {
 	return &t.waitGroup
 }
 
+// Logger implements core.TestCase.
+func (t *TestCase) Logger() *logrus.Entry {
+	return t.logger
+}
+
+// SlogLogger implements core.TestCase.
+func (t *TestCase) SlogLogger() *slog.Logger {
+	return t.slogLogger
+}
+
+// Returns the artifacts published by the test case.
+func (t *TestCase) Artifacts() []results.Artifact {
+	return t.broker.Published()
+}
+
+// Finishes publishing the artifacts of the test case, which must have finished.
+func (t *TestCase) FinishArtifacts() {
+	t.broker.Finish()
+}
+
+// Returns the broker the named diagnostics collector publishes with once the
+// test case has finished, to `<test_case_name>/diagnostics/<collector>`.
+func (t *TestCase) DiagnosticsBroker(collector string) stormartifacts.ArtifactBroker {
+	return t.broker.Sub(path.Join(artifacts.DiagnosticsDirName, collector))
+}
+
 // ArtifactBroker implements core.TestCase.
 func (t *TestCase) ArtifactBroker() stormartifacts.ArtifactBroker {
 	return t.broker
 }
+
+// Fixtures implements core.TestCase.
+func (t *TestCase) Fixtures() stormfixtures.Provider {
+	return t.fixtures
+}
+
+// State implements core.TestCase.
+func (t *TestCase) State() state.Store {
+	return t.state
+}
@@ -1,13 +1,26 @@
 package artifacts
 
+import "io"
+
+// Metadata describes an artifact being published.
+type Metadata struct {
+	// MIME type of the artifact's contents. When empty, it is guessed from
+	// the name and contents of the artifact.
+	ContentType string
+
+	// Human readable description of the artifact, shown in reports.
+	Description string
+}
+
 type ArtifactBroker interface {
 	// Allows the test case to publish a log file located at the given path to
-	// the output directory given to the runnable, when provided. If a log
-	// directory was not provided to the runnable, this function will be a
-	// no-op.
+	// the log directory of the run: the one given to the runnable with `-l`,
+	// or otherwise a new run directory. The file is only dropped, with a
+	// warning, when the run has no log directory because of `--no-run-dir` or
+	// because its run directory could not be created.
 	//
 	// The path must be an absolute path to a file on the local filesystem. When
-	// successful, the log file will be copied to the output directory and
+	// successful, the log file will be copied to the log directory and
 	// renamed to `<log_dir>/<test_case_name>/<name>`. If multiple files are
 	// published with the same name, only the last one will be kept. The
 	// `<name>` may be a path, in which case the directories will be created as
@@ -16,4 +29,21 @@ type ArtifactBroker interface {
 	// If the path does not resolve to a file, or any other error occurs, the
 	// test will be marked as an error.
 	PublishLogFile(name string, path string)
+
+	// Same as PublishLogFile, for any kind of file, with the given metadata.
+	PublishFile(name string, path string, metadata Metadata)
+
+	// Publishes the directory at the given path and everything in it to
+	// `<log_dir>/<test_case_name>/<name>`. Symbolic links are copied as links.
+	// Errors are handled as in PublishLogFile.
+	PublishDirectory(name string, path string, metadata Metadata)
+
+	// Publishes the given data as a file at `<log_dir>/<test_case_name>/<name>`.
+	// Errors are handled as in PublishLogFile.
+	PublishBytes(name string, data []byte, metadata Metadata)
+
+	// Publishes everything read from the given reader as a file at
+	// `<log_dir>/<test_case_name>/<name>`. Errors are handled as in
+	// PublishLogFile.
+	PublishReader(name string, reader io.Reader, metadata Metadata)
 }
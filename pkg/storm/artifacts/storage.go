@@ -0,0 +1,20 @@
+package artifacts
+
+import "context"
+
+// Storage stores artifacts outside of the log directory, such as in an object
+// store. Once a test case has finished, its artifacts are uploaded in the
+// background from the log directory, and the resulting URLs are recorded in
+// the results.
+//
+// Implementations must be safe for concurrent use.
+type Storage interface {
+	// Uploads the file at the given local path under the given key, a
+	// slash-separated relative path. Failed uploads are retried.
+	Upload(ctx context.Context, key string, path string) error
+
+	// Returns the URL an object uploaded under the given key can be retrieved
+	// from. For keys of directories, it is the URL all of the objects in it
+	// start with.
+	URL(key string) string
+}
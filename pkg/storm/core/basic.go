@@ -2,6 +2,7 @@ package core
 
 import (
 	"fmt"
+	"log/slog"
 	"regexp"
 
 	"github.com/sirupsen/logrus"
@@ -44,4 +45,10 @@ type Argumented interface {
 type LoggerProvider interface {
 	// Logger returns the logger to be used for logging.
 	Logger() *logrus.Logger
+
+	// SlogLogger returns a log/slog logger to be used for logging. While a
+	// test case is running, its records are recorded in the test case's
+	// captured output, keeping their attributes. Otherwise they are written
+	// to the console, following the suite's verbosity.
+	SlogLogger() *slog.Logger
 }
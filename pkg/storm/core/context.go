@@ -1,6 +1,11 @@
 package core
 
-import "context"
+import (
+	"context"
+
+	"github.com/microsoft/storm/pkg/storm/artifacts"
+	"github.com/microsoft/storm/pkg/storm/fixtures"
+)
 
 type SuiteContext interface {
 	Named
@@ -26,4 +31,32 @@ type SuiteContext interface {
 
 	// Returns a context for the suite.
 	Context() context.Context
+
+	// Returns all reporters registered in the suite.
+	Reporters() []Reporter
+
+	// Returns the storage artifacts are uploaded to, if any was set in the
+	// suite.
+	ArtifactStorage() artifacts.Storage
+
+	// Returns the artifact broker of the suite. While a scenario or helper
+	// runs, artifacts are saved to `<log_dir>/_suite`; otherwise they are
+	// dropped.
+	ArtifactBroker() artifacts.ArtifactBroker
+
+	// Returns all fixtures registered in the suite.
+	Fixtures() []*fixtures.Fixture
+
+	// Returns the values of the suite-scoped fixtures, which are torn down
+	// when the suite exits.
+	SuiteFixtures() fixtures.Instances
+
+	// Returns the hooks registered in the suite to run before and after every
+	// test case, in registration order.
+	BeforeEachHooks() []NamedTestCaseHook
+	AfterEachHooks() []NamedTestCaseHook
+
+	// Returns the diagnostics collectors registered in the suite, in
+	// registration order.
+	DiagnosticsCollectors() []NamedDiagnosticsCollector
 }
@@ -0,0 +1,46 @@
+package core
+
+import (
+	"io"
+	"time"
+)
+
+// ExecOptions customizes how a command is run by TestCase.ExecWithOptions.
+type ExecOptions struct {
+	// Working directory of the command. Defaults to the current directory.
+	Dir string
+
+	// Environment of the command, in the same format as exec.Cmd.Env. Defaults
+	// to the environment of the current process.
+	Env []string
+
+	// Standard input of the command. Defaults to no input.
+	Stdin io.Reader
+
+	// Prefix added to every line of the command's output in the test case's
+	// captured output. Defaults to the base name of the command.
+	Prefix string
+
+	// When set, the full transcript of the command is published as a log file
+	// artifact with this name through the test case's artifact broker.
+	Transcript string
+}
+
+// ExecResult describes the outcome of a command run by a test case.
+type ExecResult struct {
+	// The command line that was run.
+	Command string
+
+	// The exit code of the command. This is -1 if the command could not be
+	// started or was terminated by a signal.
+	ExitCode int
+
+	// How long the command ran for.
+	Duration time.Duration
+
+	// The full standard output of the command.
+	Stdout string
+
+	// The full standard error of the command.
+	Stderr string
+}
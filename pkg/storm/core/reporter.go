@@ -0,0 +1,41 @@
+package core
+
+import "github.com/microsoft/storm/pkg/storm/results"
+
+// Reporter receives notifications about the progress of a scenario or helper
+// run. Reporters are registered on the suite and are invoked after storm's
+// built-in reporters, in registration order.
+type Reporter interface {
+	// Called once before the run starts, before the registrant's setup is
+	// called. Returning an error aborts the run.
+	RunStarted(info results.RunInfo) error
+
+	// Called right before a test case starts running.
+	TestCaseStarted(info results.TestCaseInfo)
+
+	// Called once a test case has reached a final status. This is also called
+	// for test cases that were never run.
+	TestCaseFinished(result results.TestCaseResult)
+
+	// Called once after all test cases and the registrant's cleanup have
+	// finished, or after the registrant's setup failed. Returning an error
+	// marks the run as failed.
+	RunFinished(result results.RunResult) error
+}
+
+// BaseReporter is a no-op implementation of the Reporter interface. It is
+// meant to be used for composition when not all methods of the Reporter
+// interface are needed.
+type BaseReporter struct{}
+
+func (r BaseReporter) RunStarted(results.RunInfo) error {
+	return nil
+}
+
+func (r BaseReporter) TestCaseStarted(results.TestCaseInfo) {}
+
+func (r BaseReporter) TestCaseFinished(results.TestCaseResult) {}
+
+func (r BaseReporter) RunFinished(results.RunResult) error {
+	return nil
+}
@@ -1,8 +1,25 @@
 package core
 
+import (
+	"context"
+	"time"
+
+	"github.com/microsoft/storm/pkg/storm/artifacts"
+)
+
 type SetupCleanupContext interface {
 	LoggerProvider
 	TestRegistrantMetadata
+
+	// Returns the context of the setup or cleanup. The setup's is cancelled
+	// when the run is interrupted. The cleanup's is not, so that it can still
+	// release what the run used; it is cancelled once the cleanup is given up
+	// on, at the end of the grace period or at the maximum duration.
+	Context() context.Context
+
+	// Returns the artifact broker of the setup or cleanup. Artifacts are saved
+	// to `<log_dir>/_setup` or `<log_dir>/_cleanup`.
+	ArtifactBroker() artifacts.ArtifactBroker
 }
 
 type SetupCleanup interface {
@@ -12,3 +29,17 @@ type SetupCleanup interface {
 	/// Cleanup after running the runnable
 	Cleanup(SetupCleanupContext) error
 }
+
+// SetupFailureCleanup can be implemented by runnables implementing
+// SetupCleanup to clean up after a failed setup, which may have been only
+// partially done. When implemented, it is called instead of Cleanup with the
+// error returned by Setup.
+type SetupFailureCleanup interface {
+	CleanupAfterSetupFailure(ctx SetupCleanupContext, setupErr error) error
+}
+
+// TimeLimited can be implemented by runnables to declare how long they may run
+// by default. The --max-duration flag takes precedence over it.
+type TimeLimited interface {
+	MaxDuration() time.Duration
+}
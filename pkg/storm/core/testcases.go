@@ -2,10 +2,16 @@ package core
 
 import (
 	"context"
+	"log/slog"
 	"sync"
 	"time"
 
 	"github.com/microsoft/storm/pkg/storm/artifacts"
+	"github.com/microsoft/storm/pkg/storm/fixtures"
+	"github.com/microsoft/storm/pkg/storm/results"
+	"github.com/microsoft/storm/pkg/storm/state"
+
+	"github.com/sirupsen/logrus"
 )
 
 type TestCase interface {
@@ -42,11 +48,24 @@ type TestCase interface {
 	// Get the test case run time
 	RunTime() time.Duration
 
+	// Returns the status of the test case. It is running while the test case
+	// and its BeforeEach hooks run, and final in its AfterEach hooks.
+	Status() results.TestCaseStatus
+
+	// Returns the reason given for the status of the test case, if any.
+	Reason() string
+
 	// Registers a cleanup function to be called after all subsequent test cases
 	// in the suite have finished, regardless of their status. Cleanup functions
 	// are called in reverse order of registration.
 	SuiteCleanup(f func())
 
+	// Same as SuiteCleanup, for a cleanup function taking a context. The
+	// context is not cancelled when the run is interrupted, so that the
+	// function can still clean up; it is cancelled once the function is given
+	// up on, at the end of the grace period or at the maximum duration.
+	SuiteCleanupWithContext(f func(ctx context.Context))
+
 	// Provides a context for the test case. The context will be cancelled once
 	// the test case has finished running, making it suitable to terminate any
 	// leftover goroutines that were started by the test case.
@@ -58,7 +77,75 @@ type TestCase interface {
 	// not start before the previous one has finished all its background work.
 	BackgroundWaitGroup() *sync.WaitGroup
 
+	// Provides a logger dedicated to the test case, with fields identifying
+	// the suite, the registrant and the test case. Entries at every level are
+	// recorded in the test case's captured output, without going through the
+	// standard logrus logger. The console only shows entries allowed by the
+	// suite's verbosity, while saved logs and reports keep all of them.
+	Logger() *logrus.Entry
+
+	// Same as Logger, for code using log/slog. Attributes of the records are
+	// kept in structured results.
+	SlogLogger() *slog.Logger
+
 	// Provides an artifact broker that can be used to publish artifacts from
 	// the test case.
 	ArtifactBroker() artifacts.ArtifactBroker
+
+	// Provides the fixtures registered in the suite, set up the first time
+	// they are requested in their scope. Use fixtures.Get or
+	// fixtures.GetNamed to request them.
+	Fixtures() fixtures.Provider
+
+	// Provides the values shared between the test cases of the run. Use
+	// state.Put and state.Get to store and read them.
+	State() state.Store
+
+	// Runs a command, streaming its stdout and stderr into the test case's
+	// captured output with the command name as a prefix. The command is killed
+	// when the test case's context is cancelled. As with exec.Cmd.Run, an error
+	// is returned if the command could not be started or did not exit
+	// successfully; the result is filled in either way.
+	Exec(name string, args ...string) (ExecResult, error)
+
+	// Same as Exec, with options to customize how the command is run.
+	ExecWithOptions(opts ExecOptions, name string, args ...string) (ExecResult, error)
+}
+
+// TestCaseHook is run before or after a test case, which it receives.
+type TestCaseHook = func(TestCase) error
+
+// BeforeEachHook can be implemented by scenarios and helpers to run code
+// before each of their test cases, after the hooks registered in the suite.
+// When it returns an error, the test case is not run. It may also stop the
+// test case, for instance with Skip.
+type BeforeEachHook interface {
+	BeforeEach(tc TestCase) error
+}
+
+// AfterEachHook can be implemented by scenarios and helpers to run code after
+// each of their test cases that started, before the hooks registered in the
+// suite. The status of the test case is final and cannot be changed; its
+// context is renewed for the hooks.
+type AfterEachHook interface {
+	AfterEach(tc TestCase) error
+}
+
+// NamedTestCaseHook is a hook registered in the suite.
+type NamedTestCaseHook struct {
+	Name string
+	Hook TestCaseHook
+}
+
+// DiagnosticsCollector collects diagnostics after a test case failed or
+// errored, which it receives along with the broker to publish them with. The
+// status of the test case is final; its context is renewed for the collector
+// and cancelled when the diagnostics budget runs out.
+type DiagnosticsCollector = func(tc TestCase, broker artifacts.ArtifactBroker) error
+
+// NamedDiagnosticsCollector is a diagnostics collector registered in the
+// suite. Its artifacts are published to `<test_case_name>/diagnostics/<name>`.
+type NamedDiagnosticsCollector struct {
+	Name    string
+	Collect DiagnosticsCollector
 }
@@ -0,0 +1,187 @@
+// Package fixtures defines fixtures: resources shared by test cases, such as
+// a provisioned VM or a client, that are set up the first time a test case
+// requests them and torn down at the end of their scope.
+package fixtures
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/microsoft/storm/pkg/storm/results"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Scope determines how long a fixture lives, and thus which test cases share
+// it.
+type Scope int
+
+const (
+	// The fixture is shared by everything the suite runs, and torn down when
+	// the suite exits.
+	ScopeSuite Scope = iota
+
+	// The fixture is shared by the test cases of a scenario or helper run, and
+	// torn down after its cleanup.
+	ScopeScenario
+
+	// The fixture is set up for each test case requesting it, and torn down
+	// once the test case has finished.
+	ScopeTestCase
+)
+
+func (s Scope) String() string {
+	switch s {
+	case ScopeSuite:
+		return "suite"
+	case ScopeScenario:
+		return "scenario"
+	case ScopeTestCase:
+		return "test case"
+	default:
+		return "unknown"
+	}
+}
+
+// Context is given to the setup and teardown functions of fixtures.
+type Context interface {
+	// Returns a context for the setup or teardown. During the setup, it is
+	// cancelled with the scope of the fixture, such as when the run is
+	// interrupted. It is never cancelled during the teardown.
+	Context() context.Context
+
+	// Returns a logger for the setup or teardown. The setup logs into the
+	// output of the test case that requested the fixture.
+	Logger() *logrus.Entry
+}
+
+// Fixture is a named resource with a scope, created by New and registered in
+// the suite with AddFixture.
+type Fixture struct {
+	name     string
+	scope    Scope
+	typ      reflect.Type
+	setup    func(Context) (any, error)
+	teardown func(Context, any) error
+}
+
+// New creates a fixture with the given name and scope. setup creates its
+// value; teardown, if not nil, releases it at the end of the scope. Test cases
+// request the value by type with Get or by name with GetNamed.
+func New[T any](name string, scope Scope, setup func(Context) (T, error), teardown func(Context, T) error) *Fixture {
+	fixture := &Fixture{
+		name:  name,
+		scope: scope,
+		typ:   reflect.TypeFor[T](),
+		setup: func(ctx Context) (any, error) {
+			return setup(ctx)
+		},
+	}
+
+	if teardown != nil {
+		fixture.teardown = func(ctx Context, value any) error {
+			return teardown(ctx, value.(T))
+		}
+	}
+
+	return fixture
+}
+
+// Returns the name of the fixture.
+func (f *Fixture) Name() string {
+	return f.name
+}
+
+// Returns the scope of the fixture.
+func (f *Fixture) Scope() Scope {
+	return f.scope
+}
+
+// Returns the type of the values of the fixture.
+func (f *Fixture) Type() reflect.Type {
+	return f.typ
+}
+
+// Sets up a value of the fixture.
+func (f *Fixture) Setup(ctx Context) (any, error) {
+	return f.setup(ctx)
+}
+
+// Tears down a value of the fixture, if it has a teardown function.
+func (f *Fixture) Teardown(ctx Context, value any) error {
+	if f.teardown == nil {
+		return nil
+	}
+
+	return f.teardown(ctx, value)
+}
+
+// Requester is the test case requesting a fixture.
+type Requester interface {
+	Name() string
+	Logger() *logrus.Entry
+}
+
+// Instances holds the values of the fixtures of a scope, which are set up the
+// first time they are requested. It is implemented by storm.
+type Instances interface {
+	// Returns the value of the given fixture, setting it up for the given
+	// requester if it was not yet. The time the setup took is returned when
+	// it happened during this call. A failed setup is not retried: its error
+	// is returned to every requester.
+	Get(fixture *Fixture, requester Requester) (value any, setup time.Duration, err error)
+
+	// Returns how the setup and teardown of the fixtures went so far.
+	Results() []results.FixtureResult
+}
+
+// Provider gives test cases the values of the fixtures registered in the
+// suite. It is implemented by storm.
+type Provider interface {
+	// Returns the value of the fixture with the given name.
+	Fixture(name string) (any, error)
+
+	// Returns the value of the only fixture whose values are assignable to the
+	// given type.
+	FixtureOfType(typ reflect.Type) (any, error)
+}
+
+// Get returns the value of the only fixture of type T registered in the suite,
+// setting it up if needed.
+func Get[T any](tc interface{ Fixtures() Provider }) (T, error) {
+	value, err := tc.Fixtures().FixtureOfType(reflect.TypeFor[T]())
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	return convert[T](value)
+}
+
+// GetNamed returns the value of the fixture with the given name, setting it up
+// if needed. Its values must be of type T.
+func GetNamed[T any](tc interface{ Fixtures() Provider }, name string) (T, error) {
+	value, err := tc.Fixtures().Fixture(name)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	return convert[T](value)
+}
+
+func convert[T any](value any) (T, error) {
+	var zero T
+	if value == nil {
+		return zero, nil
+	}
+
+	converted, ok := value.(T)
+	if !ok {
+		return zero, fmt.Errorf("fixture value of type %T is not a %s", value, reflect.TypeFor[T]())
+	}
+
+	return converted, nil
+}
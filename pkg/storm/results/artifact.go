@@ -0,0 +1,77 @@
+package results
+
+import "time"
+
+// ArtifactKind identifies what was published as an artifact.
+type ArtifactKind string
+
+const (
+	ArtifactKindFile      ArtifactKind = "file"
+	ArtifactKindDirectory ArtifactKind = "directory"
+)
+
+// Artifact describes an artifact published by a test case.
+type Artifact struct {
+	// Name the artifact was published under.
+	Name string `json:"name"`
+
+	// Location of the artifact, relative to the log directory of the run.
+	Path string `json:"path"`
+
+	// Whether the artifact is a single file or a directory.
+	Kind ArtifactKind `json:"kind"`
+
+	// MIME type of the artifact's contents, for files.
+	ContentType string `json:"contentType,omitempty"`
+
+	// Description of the artifact given by the test case.
+	Description string `json:"description,omitempty"`
+
+	// Size of the artifact as stored, in bytes. For directories, the total
+	// size of the files in it.
+	Size int64 `json:"size"`
+
+	// Compression applied to the stored files, if any, e.g. "gzip". Path then
+	// includes the compression suffix.
+	Compression string `json:"compression,omitempty"`
+
+	// Path of the tarball holding the artifact when the test case's artifacts
+	// were bundled, relative to the log directory. Path is then the location
+	// of the artifact inside the tarball.
+	Bundle string `json:"bundle,omitempty"`
+
+	// Whether only the beginning of the artifact was stored because of a size
+	// limit.
+	Truncated bool `json:"truncated,omitempty"`
+
+	// Whether the artifact was not stored at all because of a size limit.
+	Rejected bool `json:"rejected,omitempty"`
+
+	// Why the artifact was truncated or rejected.
+	Reason string `json:"reason,omitempty"`
+
+	// URL the artifact was uploaded to when an artifact storage is used. For
+	// bundled artifacts, the URL of the bundle.
+	URL string `json:"url,omitempty"`
+
+	// Why the artifact could not be uploaded to the artifact storage, if it
+	// failed.
+	UploadError string `json:"uploadError,omitempty"`
+
+	// Time at which the artifact was published.
+	Time time.Time `json:"time"`
+}
+
+// Location returns the path of the file or directory holding the artifact,
+// relative to the log directory: the bundle for bundled artifacts, nothing for
+// rejected ones.
+func (a Artifact) Location() string {
+	switch {
+	case a.Rejected:
+		return ""
+	case a.Bundle != "":
+		return a.Bundle
+	default:
+		return a.Path
+	}
+}
@@ -0,0 +1,57 @@
+package results
+
+import (
+	"slices"
+	"time"
+)
+
+// OutputStream identifies where a line of captured output came from.
+type OutputStream string
+
+const (
+	OutputStreamStdout OutputStream = "stdout"
+	OutputStreamStderr OutputStream = "stderr"
+	OutputStreamLogrus OutputStream = "logrus"
+	OutputStreamSlog   OutputStream = "slog"
+
+	// Lines added by storm itself, such as the marker of omitted output.
+	OutputStreamStorm OutputStream = "storm"
+)
+
+// Maximum length of a captured line in bytes. Longer lines are split into
+// several lines.
+const MaxOutputLineLength = 64 * 1024
+
+// OutputLine is a single line of output captured while a test case was running.
+type OutputLine struct {
+	// Time at which the line was captured.
+	Time time.Time `json:"time"`
+
+	// Stream the line was written to.
+	Stream OutputStream `json:"stream"`
+
+	// Level of the log entry the line belongs to, only set for lines logged
+	// through logrus or slog. Slog levels are named after the matching logrus
+	// level.
+	Level string `json:"level,omitempty"`
+
+	// Contents of the line, without the trailing newline.
+	Text string `json:"text"`
+
+	// Structured attributes of the log entry the line belongs to. Groups are
+	// flattened into dotted keys.
+	Attrs map[string]any `json:"attrs,omitempty"`
+}
+
+// OutputText returns the text of the captured output lines. When streams are
+// given, only lines written to those streams are returned.
+func (r TestCaseResult) OutputText(streams ...OutputStream) []string {
+	lines := make([]string, 0, len(r.Output))
+	for _, line := range r.Output {
+		if len(streams) == 0 || slices.Contains(streams, line.Stream) {
+			lines = append(lines, line.Text)
+		}
+	}
+
+	return lines
+}
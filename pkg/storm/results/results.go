@@ -0,0 +1,193 @@
+// Package results defines the data model storm uses to describe the outcome of
+// a scenario or helper run. It is the data handed to reporters.
+package results
+
+import "time"
+
+// RunInfo describes a run of a scenario or helper.
+type RunInfo struct {
+	// Name of the suite the registrant belongs to.
+	Suite string `json:"suite"`
+
+	// Type of the registrant, either "scenario" or "helper".
+	RegistrantType string `json:"registrantType"`
+
+	// Name of the scenario or helper being run.
+	Registrant string `json:"registrant"`
+
+	// Tags of the scenario being run. Empty for helpers.
+	Tags []string `json:"tags,omitempty"`
+
+	// Stage paths of the scenario being run. Empty for helpers.
+	StagePaths []string `json:"stagePaths,omitempty"`
+
+	// Extra arguments passed to the scenario or helper.
+	Args []string `json:"args,omitempty"`
+
+	// Name of the host the run took place on.
+	Hostname string `json:"hostname,omitempty"`
+
+	// Directory logs and artifacts of the run are saved to, if any.
+	LogDir string `json:"logDir,omitempty"`
+
+	// Time at which the run started.
+	StartTime time.Time `json:"startTime"`
+
+	// Names of all the test cases in the run, in execution order.
+	TestCases []string `json:"testCases"`
+}
+
+// TestCaseInfo describes a test case that is about to run.
+type TestCaseInfo struct {
+	// Name of the test case.
+	Name string `json:"name"`
+
+	// Position of the test case in the run, starting at 0.
+	Index int `json:"index"`
+}
+
+// TestCaseResult describes the outcome of a single test case.
+type TestCaseResult struct {
+	TestCaseInfo
+
+	// Final status of the test case.
+	Status TestCaseStatus `json:"status"`
+
+	// Reason given for the status, if any. When the test case was closed with
+	// an error, this is the error message.
+	Reason string `json:"reason,omitempty"`
+
+	// Stack trace of the panic that closed the test case, if any.
+	Stack string `json:"stack,omitempty"`
+
+	// Time at which the test case started. Zero if the test case never ran.
+	StartTime time.Time `json:"startTime"`
+
+	// Time the test case took to run. Zero if the test case never ran. Stored
+	// in nanoseconds when serialized.
+	Duration time.Duration `json:"duration"`
+
+	// Output captured while the test case was running, one entry per line.
+	// Long outputs only keep their first and last lines, with a marker line
+	// in between.
+	Output []OutputLine `json:"output,omitempty"`
+
+	// Number of lines left out of Output.
+	OutputOmitted int `json:"outputOmitted,omitempty"`
+
+	// Artifacts published by the test case, in the order they were
+	// published.
+	Artifacts []Artifact `json:"artifacts,omitempty"`
+
+	// Failures of the hooks run before and after the test case. They do not
+	// change its status.
+	HookFailures []HookFailure `json:"hookFailures,omitempty"`
+
+	// Whether the test case was not run again because it passed in the run
+	// this one resumed. Its timings are those of that run.
+	PreviouslyPassed bool `json:"previouslyPassed,omitempty"`
+
+	// Whether the test case runs again because it did not pass in the earlier
+	// run whose failures are rerun.
+	Rerun bool `json:"rerun,omitempty"`
+
+	// Time the test case spent setting up the fixtures it requested, included
+	// in Duration. Stored in nanoseconds when serialized.
+	FixtureSetup time.Duration `json:"fixtureSetup,omitempty"`
+
+	// Path to a file holding the full output, formatted as in saved logs.
+	// Only available while the run is being reported, it is not serialized.
+	OutputFile string `json:"-"`
+}
+
+// HookFailure describes a hook run before or after a test case that failed.
+type HookFailure struct {
+	// Name of the hook, such as "BeforeEach" for the hook of the scenario or
+	// helper, or "suite AfterEach 'name'" for a hook registered in the suite.
+	Hook string `json:"hook"`
+
+	// Error returned by the hook.
+	Error string `json:"error"`
+
+	// Stack trace of the panic of the hook, if any.
+	Stack string `json:"stack,omitempty"`
+}
+
+// FixtureResult describes the setup and teardown of a fixture in a scope.
+type FixtureResult struct {
+	// Name of the fixture.
+	Name string `json:"name"`
+
+	// Scope of the fixture: "suite", "scenario" or "test case".
+	Scope string `json:"scope"`
+
+	// Name of the test case whose request set the fixture up.
+	RequestedBy string `json:"requestedBy"`
+
+	// Time the setup took. Stored in nanoseconds when serialized.
+	SetupDuration time.Duration `json:"setupDuration"`
+
+	// Error of the setup, if it failed.
+	SetupError string `json:"setupError,omitempty"`
+
+	// Whether the fixture was torn down. Suite fixtures are torn down after
+	// the results are reported.
+	TornDown bool `json:"tornDown,omitempty"`
+
+	// Time the teardown took. Stored in nanoseconds when serialized.
+	TeardownDuration time.Duration `json:"teardownDuration,omitempty"`
+
+	// Error of the teardown, if it failed.
+	TeardownError string `json:"teardownError,omitempty"`
+}
+
+// RunResult describes the outcome of a complete run of a scenario or helper.
+type RunResult struct {
+	RunInfo `json:"info"`
+
+	// Total duration of the run. Stored in nanoseconds when serialized.
+	Duration time.Duration `json:"duration"`
+
+	// Results of all test cases, in execution order.
+	TestCases []TestCaseResult `json:"testCases"`
+
+	// Artifacts published outside of test cases: during the setup, through
+	// the suite and during the cleanup.
+	Artifacts []Artifact `json:"artifacts,omitempty"`
+
+	// Result of the registrant's setup, with its captured output. Only
+	// present when the registrant has a setup.
+	Setup *TestCaseResult `json:"setup,omitempty"`
+
+	// Result of the registrant's cleanup, with its captured output. Only
+	// present when the registrant has a cleanup and it ran.
+	Cleanup *TestCaseResult `json:"cleanup,omitempty"`
+
+	// Fixtures set up during the run: suite fixtures first, then scenario
+	// fixtures and the test case fixtures of each test case.
+	Fixtures []FixtureResult `json:"fixtures,omitempty"`
+
+	// Values the test cases shared at the end of the run, for debugging.
+	State []StateEntry `json:"state,omitempty"`
+}
+
+// StateEntry describes a value shared between the test cases of a run.
+type StateEntry struct {
+	// Key the value is stored under.
+	Key string `json:"key"`
+
+	// Name of the test case that stored the value last.
+	Producer string `json:"producer"`
+
+	// Go type of the value.
+	Type string `json:"type"`
+
+	// Value formatted with %+v, truncated if it is too long.
+	Value string `json:"value"`
+}
+
+// Report is a collection of run results. It is the document storm reads and
+// writes as JSON results files.
+type Report struct {
+	Runs []RunResult `json:"runs"`
+}
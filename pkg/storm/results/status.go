@@ -0,0 +1,152 @@
+package results
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+)
+
+type TestCaseStatus int
+
+const (
+	TestCaseStatusPending TestCaseStatus = iota
+	TestCaseStatusRunning
+	TestCaseStatusPassed
+	TestCaseStatusFailed
+	TestCaseStatusSkipped
+	TestCaseStatusNotRun
+	TestCaseStatusError
+)
+
+// IsFinal returns true for all test case statuses that are considered final states.
+// This includes Passed, Failed, Skipped, and Error statuses.
+// It does not include Pending or Running statuses.
+func (tcs TestCaseStatus) IsFinal() bool {
+	return tcs == TestCaseStatusPassed ||
+		tcs == TestCaseStatusFailed ||
+		tcs == TestCaseStatusSkipped ||
+		tcs == TestCaseStatusNotRun ||
+		tcs == TestCaseStatusError
+}
+
+// Ran returns whether the test case was actually executed. This is true for
+// Passed, Failed, and Error statuses. It is false for Pending, Running,
+// Skipped, and NotRun statuses.
+func (tcs TestCaseStatus) Ran() bool {
+	return tcs == TestCaseStatusPassed ||
+		tcs == TestCaseStatusFailed ||
+		tcs == TestCaseStatusError
+}
+
+func (tcs TestCaseStatus) String() string {
+	switch tcs {
+	case TestCaseStatusPending:
+		return "PEND"
+	case TestCaseStatusPassed:
+		return "PASS"
+	case TestCaseStatusFailed:
+		return "FAIL"
+	case TestCaseStatusSkipped:
+		return "SKIP"
+	case TestCaseStatusNotRun:
+		return "NOTR"
+	case TestCaseStatusError:
+		return "ERRO"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// Names of the statuses in saved results. Unlike String, which is meant for
+// display, every status has its own name so that it can be read back.
+var testCaseStatusNames = map[TestCaseStatus]string{
+	TestCaseStatusPending: "PEND",
+	TestCaseStatusRunning: "RUNN",
+	TestCaseStatusPassed:  "PASS",
+	TestCaseStatusFailed:  "FAIL",
+	TestCaseStatusSkipped: "SKIP",
+	TestCaseStatusNotRun:  "NOTR",
+	TestCaseStatusError:   "ERRO",
+}
+
+// ParseTestCaseStatus parses the name of a status in saved results, as
+// returned by MarshalText.
+func ParseTestCaseStatus(s string) (TestCaseStatus, error) {
+	for tcs, name := range testCaseStatusNames {
+		if name == s {
+			return tcs, nil
+		}
+	}
+
+	return TestCaseStatusPending, fmt.Errorf("unknown test case status '%s'", s)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (tcs TestCaseStatus) MarshalText() ([]byte, error) {
+	name, ok := testCaseStatusNames[tcs]
+	if !ok {
+		return nil, fmt.Errorf("unknown test case status %d", int(tcs))
+	}
+
+	return []byte(name), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (tcs *TestCaseStatus) UnmarshalText(text []byte) error {
+	parsed, err := ParseTestCaseStatus(string(text))
+	if err != nil {
+		return err
+	}
+
+	*tcs = parsed
+	return nil
+}
+
+func (tcs TestCaseStatus) ColorString() string {
+	color.NoColor = false // Force colors
+	switch tcs {
+	case TestCaseStatusPassed:
+		return color.GreenString(tcs.String())
+	case TestCaseStatusFailed:
+		return color.RedString(tcs.String())
+	case TestCaseStatusSkipped:
+		return color.YellowString(tcs.String())
+	case TestCaseStatusError:
+		return color.New(color.FgRed, color.Bold).Sprint(tcs.String())
+	default:
+		return tcs.String()
+	}
+}
+
+func (tcs TestCaseStatus) IsPending() bool {
+	return tcs == TestCaseStatusPending
+}
+
+func (tcs TestCaseStatus) IsRunning() bool {
+	return tcs == TestCaseStatusRunning
+}
+
+func (tcs TestCaseStatus) Passed() bool {
+	return tcs == TestCaseStatusPassed
+}
+
+func (tcs TestCaseStatus) Failed() bool {
+	return tcs == TestCaseStatusFailed
+}
+
+func (tcs TestCaseStatus) Skipped() bool {
+	return tcs == TestCaseStatusSkipped
+}
+
+func (tcs TestCaseStatus) Errored() bool {
+	return tcs == TestCaseStatusError
+}
+
+func (tcs TestCaseStatus) NotRun() bool {
+	return tcs == TestCaseStatusNotRun
+}
+
+// IsBad returns true if the test case status is either Failed or Error.
+func (tcs TestCaseStatus) IsBad() bool {
+	return tcs == TestCaseStatusFailed || tcs == TestCaseStatusError
+}
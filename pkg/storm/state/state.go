@@ -0,0 +1,129 @@
+// Package state lets the test cases of a run share typed values: a test case
+// puts a value under a key, and the following test cases get it back. Reading
+// a value that no previous test case stored stops the reader with a reason
+// naming the test case that should have stored it.
+//
+// Values of persistent keys are saved to the checkpoint of the run as JSON, so
+// that a run resumed from it gets back the values stored by the test cases it
+// skips.
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// Key identifies a value of type T shared between test cases, stored by the
+// test case named producer.
+type Key[T any] struct {
+	name       string
+	producer   string
+	persistent bool
+}
+
+// NewKey creates a key for values of type T stored by the test case named
+// producer. The producer is used to explain why a value is missing; it may be
+// empty if several test cases store the value.
+func NewKey[T any](name string, producer string) Key[T] {
+	return Key[T]{
+		name:     name,
+		producer: producer,
+	}
+}
+
+// Returns the name of the key.
+func (k Key[T]) Name() string {
+	return k.name
+}
+
+// Returns the name of the test case storing the values of the key.
+func (k Key[T]) Producer() string {
+	return k.producer
+}
+
+// Persistent returns a copy of the key whose values are saved to the
+// checkpoint of the run. T must then support encoding/json.
+func (k Key[T]) Persistent() Key[T] {
+	k.persistent = true
+	return k
+}
+
+// Returns whether the values of the key are saved to the checkpoint of the
+// run.
+func (k Key[T]) IsPersistent() bool {
+	return k.persistent
+}
+
+// Store holds the values shared between the test cases of a run, as seen by
+// one of them. It is implemented by storm.
+type Store interface {
+	// Stores the value under the given key on behalf of the test case,
+	// replacing any previous value. Values of persistent keys are saved to the
+	// checkpoint of the run.
+	Put(key string, value any, persistent bool)
+
+	// Returns the value stored under the given key, if any. Values restored
+	// from a checkpoint are returned as json.RawMessage until stored again.
+	Lookup(key string) (any, bool)
+
+	// Stops the test case because the value of the given key is missing. The
+	// test case is marked as not run when the producer did not pass, and as
+	// errored otherwise, with a reason naming the producer. It does not return.
+	Missing(key string, producer string)
+
+	// Stops the test case with the given error. It does not return.
+	Error(err error)
+}
+
+// Put stores the value under the given key, for the following test cases.
+func Put[T any](tc interface{ State() Store }, key Key[T], value T) {
+	tc.State().Put(key.name, value, key.persistent)
+}
+
+// Lookup returns the value stored under the given key, if a previous test case
+// stored one.
+func Lookup[T any](tc interface{ State() Store }, key Key[T]) (T, bool, error) {
+	var zero T
+	value, ok := tc.State().Lookup(key.name)
+	if !ok {
+		return zero, false, nil
+	}
+
+	if value == nil {
+		return zero, true, nil
+	}
+
+	typed, ok := value.(T)
+	if ok {
+		return typed, true, nil
+	}
+
+	// The value was restored from a checkpoint.
+	if raw, ok := value.(json.RawMessage); ok {
+		err := json.Unmarshal(raw, &typed)
+		if err != nil {
+			return zero, true, fmt.Errorf("failed to decode state '%s' restored from the checkpoint as a %s: %w", key.name, reflect.TypeFor[T](), err)
+		}
+
+		return typed, true, nil
+	}
+
+	return zero, true, fmt.Errorf("state '%s' holds a %T, not a %s", key.name, value, reflect.TypeFor[T]())
+}
+
+// Get returns the value stored under the given key. When no previous test
+// case stored one, or it has another type, the test case is stopped as
+// described by Store.Missing and Store.Error.
+func Get[T any](tc interface{ State() Store }, key Key[T]) T {
+	value, ok, err := Lookup(tc, key)
+	if err != nil {
+		tc.State().Error(err)
+	}
+
+	if !ok {
+		tc.State().Missing(key.name, key.producer)
+	}
+
+	return value
+}
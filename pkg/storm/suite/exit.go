@@ -1,10 +1,12 @@
 package suite
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
 	"github.com/microsoft/storm/internal/devops"
+	"github.com/microsoft/storm/internal/stormerror"
 )
 
 // Exit the program and report the exit status
@@ -18,5 +20,13 @@ func (s *StormSuite) reportExitStatus(err error) {
 		devops.LogError(fmt.Sprintf("Suite '%s' run failed: %s", s.name, err))
 	}
 
+	// Interrupted runs exit with the code of the signal, so that they can be
+	// told apart from failed ones.
+	var interrupted *stormerror.InterruptedError
+	if errors.As(err, &interrupted) {
+		s.Log.WithError(err).Errorf("Suite '%s' was interrupted", s.name)
+		os.Exit(interrupted.ExitCode())
+	}
+
 	s.Log.WithError(err).Fatalf("Suite '%s' failed", s.name)
 }
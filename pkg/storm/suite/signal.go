@@ -0,0 +1,43 @@
+package suite
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/microsoft/storm/internal/stormerror"
+)
+
+// handleSignals cancels the suite context when the suite receives SIGINT or
+// SIGTERM, so that the running scenario or helper can stop, clean up and write
+// its reports. A second signal exits immediately. The returned function stops
+// handling signals.
+func (s *StormSuite) handleSignals() (stop func()) {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	done := make(chan struct{})
+	go func() {
+		var interrupted *stormerror.InterruptedError
+		for {
+			select {
+			case <-done:
+				return
+			case sig := <-signals:
+				if interrupted != nil {
+					s.Log.Errorf("Received signal '%s' again, exiting without cleaning up", sig)
+					os.Exit(interrupted.ExitCode())
+				}
+
+				interrupted = stormerror.NewInterruptedError(sig)
+				s.Log.Warnf("Received signal '%s', stopping the run and cleaning up. Send it again to exit immediately.", sig)
+				s.cancel(interrupted)
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
@@ -3,14 +3,20 @@ package suite
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"os"
 	"reflect"
 	"runtime"
 	"slices"
 
+	internalartifacts "github.com/microsoft/storm/internal/artifacts"
 	"github.com/microsoft/storm/internal/cli"
 	"github.com/microsoft/storm/internal/collector"
+	internalfixtures "github.com/microsoft/storm/internal/fixtures"
+	"github.com/microsoft/storm/internal/slogcapture"
+	"github.com/microsoft/storm/pkg/storm/artifacts"
 	"github.com/microsoft/storm/pkg/storm/core"
+	"github.com/microsoft/storm/pkg/storm/fixtures"
 
 	"github.com/sirupsen/logrus"
 )
@@ -19,11 +25,21 @@ type StormSuite struct {
 	name        string
 	scenarios   []core.Scenario
 	ctx         context.Context
-	cancel      context.CancelFunc
+	cancel      context.CancelCauseFunc
 	Log         *logrus.Logger
+	slogLogger  *slog.Logger
 	helpers     []core.Helper
 	scripts     []any
+	reporters   []core.Reporter
+	storage     artifacts.Storage
 	azureDevops bool
+
+	artifactBroker artifacts.ArtifactBroker
+	fixtures       []*fixtures.Fixture
+	suiteFixtures  *internalfixtures.Store
+	beforeEach     []core.NamedTestCaseHook
+	afterEach      []core.NamedTestCaseHook
+	diagnostics    []core.NamedDiagnosticsCollector
 }
 
 func CreateSuite(name string) StormSuite {
@@ -43,16 +59,31 @@ func CreateSuite(name string) StormSuite {
 
 	logger.Infof("Creating suite '%s'", name)
 
-	ctx, cancel := context.WithCancel(context.Background())
+	// The slog logger records into the output of the running test case, if
+	// any, and otherwise logs to the same place as the logrus logger.
+	slogLogger := slog.New(slogcapture.NewHandler(
+		slogcapture.ActiveSink,
+		slog.NewTextHandler(stdErrCopy, &slog.HandlerOptions{
+			Level: slogcapture.LogrusLeveler{Logger: logger},
+		}),
+	))
+
+	ctx, cancel := context.WithCancelCause(context.Background())
 
 	return StormSuite{
-		name:      name,
-		ctx:       ctx,
-		cancel:    cancel,
-		scenarios: make([]core.Scenario, 0),
-		helpers:   make([]core.Helper, 0),
-		scripts:   make([]any, 0),
-		Log:       logger,
+		name:       name,
+		ctx:        ctx,
+		cancel:     cancel,
+		scenarios:  make([]core.Scenario, 0),
+		helpers:    make([]core.Helper, 0),
+		scripts:    make([]any, 0),
+		reporters:  make([]core.Reporter, 0),
+		Log:        logger,
+		slogLogger: slogLogger,
+
+		artifactBroker: internalartifacts.NewSuiteBroker(logger),
+		fixtures:       make([]*fixtures.Fixture, 0),
+		suiteFixtures:  internalfixtures.NewStore(fixtures.ScopeSuite, ctx, logrus.NewEntry(logger)),
 	}
 }
 
@@ -66,10 +97,18 @@ func (s *StormSuite) Run() {
 
 	s.Log.Infof("Running suite '%s' - %d scenarios, %d helpers collected.", s.name, len(s.scenarios), len(s.helpers))
 	kong_ctx.BindTo(s, (*core.SuiteContext)(nil))
+	stopSignals := s.handleSignals()
 	err := kong_ctx.Run()
+	stopSignals()
+
+	// Suite fixtures outlive the scenarios and helpers using them.
+	teardownErr := s.suiteFixtures.Teardown()
+	if err == nil && teardownErr != nil {
+		err = fmt.Errorf("failed to tear down suite fixtures: %w", teardownErr)
+	}
 
 	// Cancel the suite context.
-	s.cancel()
+	s.cancel(nil)
 
 	// This call will end the program.
 	s.reportExitStatus(err)
@@ -160,6 +199,95 @@ func (s *StormSuite) AddScriptSet(script any) {
 	s.scripts = append(s.scripts, script)
 }
 
+// Adds a reporter to the suite. Reporters are notified about the progress of
+// every scenario or helper run by the suite.
+func (s *StormSuite) AddReporter(reporter core.Reporter) {
+	if reporter == nil {
+		s.Log.Fatal("Cannot add a nil reporter")
+	}
+
+	s.Log.Debugf("Registering reporter %T", reporter)
+	s.reporters = append(s.reporters, reporter)
+}
+
+// Adds a fixture to the suite. Test cases request it with fixtures.Get or
+// fixtures.GetNamed, which set it up the first time it is requested in its
+// scope.
+func (s *StormSuite) AddFixture(fixture *fixtures.Fixture) {
+	if fixture == nil {
+		s.Log.Fatal("Cannot add a nil fixture")
+	}
+
+	if slices.ContainsFunc(s.fixtures, func(f *fixtures.Fixture) bool {
+		return f.Name() == fixture.Name()
+	}) {
+		s.Log.Fatalf("Fixture '%s' already exists", fixture.Name())
+	}
+
+	if err := core.ValidateEntityName(fixture.Name(), "fixture"); err != nil {
+		s.Log.WithError(err).Fatal("Failed to add fixture")
+	}
+
+	s.Log.Debugf("Registering %s fixture '%s' of type %s", fixture.Scope(), fixture.Name(), fixture.Type())
+	s.fixtures = append(s.fixtures, fixture)
+}
+
+// Adds a hook run before every test case of every scenario and helper, before
+// their own BeforeEach hook. When it returns an error, the test case is not
+// run and the failure is reported with it.
+func (s *StormSuite) AddBeforeEach(name string, hook core.TestCaseHook) {
+	if hook == nil {
+		s.Log.Fatalf("Cannot add a nil BeforeEach hook '%s'", name)
+	}
+
+	s.Log.Debugf("Registering BeforeEach hook '%s'", name)
+	s.beforeEach = append(s.beforeEach, core.NamedTestCaseHook{Name: name, Hook: hook})
+}
+
+// Adds a hook run after every test case of every scenario and helper that
+// started, after their own AfterEach hook. The hook can check the final status
+// of the test case, for instance to collect diagnostics after failures. Its
+// failures are reported with the test case without changing its status.
+func (s *StormSuite) AddAfterEach(name string, hook core.TestCaseHook) {
+	if hook == nil {
+		s.Log.Fatalf("Cannot add a nil AfterEach hook '%s'", name)
+	}
+
+	s.Log.Debugf("Registering AfterEach hook '%s'", name)
+	s.afterEach = append(s.afterEach, core.NamedTestCaseHook{Name: name, Hook: hook})
+}
+
+// Adds a diagnostics collector run after every test case that failed or
+// errored, once its status is final and before its AfterEach hooks. What it
+// publishes is saved to `<test_case_name>/diagnostics/<name>`. Its failures are
+// only logged and never change the status of the test case.
+func (s *StormSuite) AddDiagnosticsCollector(name string, collector core.DiagnosticsCollector) {
+	if collector == nil {
+		s.Log.Fatalf("Cannot add a nil diagnostics collector '%s'", name)
+	}
+
+	if slices.ContainsFunc(s.diagnostics, func(c core.NamedDiagnosticsCollector) bool {
+		return c.Name == name
+	}) {
+		s.Log.Fatalf("Diagnostics collector '%s' already exists", name)
+	}
+
+	if err := core.ValidateEntityName(name, "diagnostics collector"); err != nil {
+		s.Log.WithError(err).Fatal("Failed to add diagnostics collector")
+	}
+
+	s.Log.Debugf("Registering diagnostics collector '%s'", name)
+	s.diagnostics = append(s.diagnostics, core.NamedDiagnosticsCollector{Name: name, Collect: collector})
+}
+
+// Sets the storage artifacts are uploaded to once each test case has finished,
+// in addition to being saved to the log directory. The --artifact-storage flag
+// takes precedence over it.
+func (s *StormSuite) SetArtifactStorage(storage artifacts.Storage) {
+	s.Log.Debugf("Using artifact storage %T", storage)
+	s.storage = storage
+}
+
 // Returns the name of the suite
 func (s *StormSuite) Name() string {
 	return s.name
@@ -209,6 +337,10 @@ func (s *StormSuite) Logger() *logrus.Logger {
 	return s.Log
 }
 
+func (s *StormSuite) SlogLogger() *slog.Logger {
+	return s.slogLogger
+}
+
 func (s *StormSuite) AzureDevops() bool {
 	return s.azureDevops
 }
@@ -216,3 +348,35 @@ func (s *StormSuite) AzureDevops() bool {
 func (s *StormSuite) Context() context.Context {
 	return s.ctx
 }
+
+func (s *StormSuite) Reporters() []core.Reporter {
+	return s.reporters
+}
+
+func (s *StormSuite) ArtifactStorage() artifacts.Storage {
+	return s.storage
+}
+
+func (s *StormSuite) ArtifactBroker() artifacts.ArtifactBroker {
+	return s.artifactBroker
+}
+
+func (s *StormSuite) Fixtures() []*fixtures.Fixture {
+	return s.fixtures
+}
+
+func (s *StormSuite) SuiteFixtures() fixtures.Instances {
+	return s.suiteFixtures
+}
+
+func (s *StormSuite) BeforeEachHooks() []core.NamedTestCaseHook {
+	return s.beforeEach
+}
+
+func (s *StormSuite) AfterEachHooks() []core.NamedTestCaseHook {
+	return s.afterEach
+}
+
+func (s *StormSuite) DiagnosticsCollectors() []core.NamedDiagnosticsCollector {
+	return s.diagnostics
+}
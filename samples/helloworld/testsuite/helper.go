@@ -95,7 +95,7 @@ func (h *HelloWorldHelper) myTestCaseWithBackgroundJobs(tc storm.TestCase) error
 }
 
 func (h *HelloWorldHelper) myTestCaseWithLogs(tc storm.TestCase) error {
-	logrus.Info("This test case will generate a log file that will be published to the log directory given to the test suite.")
+	logrus.Info("This test case will generate a log file that will be published to the log directory of the run.")
 
 	// Simulate creating a log file
 	logFile1 := "/tmp/logfile1.log"
@@ -114,8 +114,8 @@ func (h *HelloWorldHelper) myTestCaseWithLogs(tc storm.TestCase) error {
 		return fmt.Errorf("failed to write to log file: %w", err)
 	}
 
-	// Publish the log file to the directory passed with `-l` option if the
-	// `run` or `helper` command.
+	// Publish the log file to the directory passed with the `-l` option of the
+	// `run` or `helper` command, or to the run directory otherwise.
 	tc.ArtifactBroker().PublishLogFile("logfile1.log", logFile1)
 
 	return nil
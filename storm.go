@@ -1,7 +1,9 @@
 package storm
 
 import (
+	"github.com/microsoft/storm/pkg/storm/artifacts"
 	"github.com/microsoft/storm/pkg/storm/core"
+	"github.com/microsoft/storm/pkg/storm/fixtures"
 	"github.com/microsoft/storm/pkg/storm/suite"
 )
 
@@ -14,14 +16,30 @@ type BaseScenario = core.BaseScenario
 type Helper = core.Helper
 type BaseHelper = core.BaseHelper
 
+type SetupCleanup = core.SetupCleanup
 type SetupCleanupContext = core.SetupCleanupContext
+type SetupFailureCleanup = core.SetupFailureCleanup
+type BeforeEachHook = core.BeforeEachHook
+type AfterEachHook = core.AfterEachHook
+type TimeLimited = core.TimeLimited
 
 type TestRegistrar = core.TestRegistrar
 type TestCase = core.TestCase
 type TestCaseFunction = core.TestCaseFunction
+type DiagnosticsCollector = core.DiagnosticsCollector
+type ExecOptions = core.ExecOptions
+type ExecResult = core.ExecResult
 
 type LoggerProvider = core.LoggerProvider
 
+type Reporter = core.Reporter
+type BaseReporter = core.BaseReporter
+
+type ArtifactMetadata = artifacts.Metadata
+type ArtifactStorage = artifacts.Storage
+
+type Fixture = fixtures.Fixture
+
 // Creates a new suite with the given name.
 func CreateSuite(name string) StormSuite {
 	return suite.CreateSuite(name)